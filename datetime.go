@@ -0,0 +1,133 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	localDateLayout     = "2006-01-02"
+	localTimeLayout     = "15:04:05.999999999"
+	localDateTimeLayout = localDateLayout + "T" + localTimeLayout
+)
+
+// LocalDate represents TOML Local Date, a calendar date without time of
+// day and offset.
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// LocalDateOf returns the LocalDate in which t occurs.
+func LocalDateOf(t time.Time) LocalDate {
+	var d LocalDate
+	d.Year, d.Month, d.Day = t.Date()
+	return d
+}
+
+// In returns time.Time at the start of d in location loc.
+func (d LocalDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// String returns d in TOML format.
+func (d LocalDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d LocalDate) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *LocalDate) UnmarshalText(b []byte) error {
+	t, err := time.Parse(localDateLayout, string(b))
+	if err != nil {
+		return err
+	}
+	*d = LocalDateOf(t)
+	return nil
+}
+
+// LocalTime represents TOML Local Time, a time of day without date and
+// offset.
+type LocalTime struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+// LocalTimeOf returns the LocalTime at which t occurs.
+func LocalTimeOf(t time.Time) LocalTime {
+	var lt LocalTime
+	lt.Hour, lt.Minute, lt.Second = t.Clock()
+	lt.Nanosecond = t.Nanosecond()
+	return lt
+}
+
+// String returns t in TOML format. Fractional seconds are omitted if
+// they are zero.
+func (t LocalTime) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond == 0 {
+		return s
+	}
+	return s + strings.TrimRight(fmt.Sprintf(".%09d", t.Nanosecond), "0")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t LocalTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *LocalTime) UnmarshalText(b []byte) error {
+	v, err := time.Parse(localTimeLayout, string(b))
+	if err != nil {
+		return err
+	}
+	*t = LocalTimeOf(v)
+	return nil
+}
+
+// LocalDateTime represents TOML Local Date-Time, a date and time of day
+// without offset.
+type LocalDateTime struct {
+	Date LocalDate
+	Time LocalTime
+}
+
+// LocalDateTimeOf returns the LocalDateTime at which t occurs.
+func LocalDateTimeOf(t time.Time) LocalDateTime {
+	return LocalDateTime{LocalDateOf(t), LocalTimeOf(t)}
+}
+
+// In returns time.Time at dt in location loc.
+func (dt LocalDateTime) In(loc *time.Location) time.Time {
+	d, t := dt.Date, dt.Time
+	return time.Date(d.Year, d.Month, d.Day, t.Hour, t.Minute, t.Second, t.Nanosecond, loc)
+}
+
+// String returns dt in TOML format.
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + "T" + dt.Time.String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (dt LocalDateTime) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (dt *LocalDateTime) UnmarshalText(b []byte) error {
+	t, err := time.Parse(localDateTimeLayout, string(b))
+	if err != nil {
+		return err
+	}
+	*dt = LocalDateTimeOf(t)
+	return nil
+}
@@ -3,17 +3,65 @@ package toml
 import (
 	"encoding"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"go/ast"
+	"io"
+	"io/ioutil"
+	"math/big"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kezhuw/toml/internal/types"
 )
 
+type decodeState struct {
+	version Version
+
+	disallowUnknownFields bool
+	unknownKeys           []UnknownKey
+
+	naming       NamingStrategy
+	durationUnit time.Duration // unit of integers decoded into time.Duration
+	useNumber    bool
+
+	missingKeys []string // paths of absent keys of required fields
+
+	collectErrors bool
+	errors        []error
+
+	decoded map[string]struct{} // paths of decoded values, nil if not tracked
+
+	src   string       // source of document being decoded
+	root  *types.Table // root table of document being decoded
+	meta  *MetaData    // positions of keys and values, built on first error
+	path  string       // key path of value being decoded
+	field string       // Go struct field chain of value being decoded
+}
+
+// markDecoded marks value at path and all its descendants as decoded.
+func (d *decodeState) markDecoded(path string, v types.Value) {
+	if d.decoded == nil {
+		return
+	}
+	d.decoded[path] = struct{}{}
+	switch v := v.(type) {
+	case *types.Table:
+		for key, value := range v.Elems {
+			d.markDecoded(combineKeyPath(path, key), value)
+		}
+	case *types.Array:
+		for i, value := range v.Elems {
+			d.markDecoded(combineIndexPath(path, i), value)
+		}
+	}
+}
+
 // An InvalidUnmarshalError describes that an invalid argment was passed
 // to Unmarshal. The argument passed to Unmarshal must be non-nil pointer.
 type InvalidUnmarshalError struct {
@@ -33,23 +81,169 @@ func (e *InvalidUnmarshalError) Error() string {
 // An UnmarshalTypeError describes that a TOML value is not appropriate
 // to be stored in specified Go type.
 type UnmarshalTypeError struct {
-	Value string
-	Type  reflect.Type
+	Value  string
+	Type   reflect.Type
+	Path   string // key path of TOML value, empty for root table
+	Field  string // Go struct field chain, empty if not in struct field
+	Line   int    // 1-based, 0 if unknown
+	Column int    // 1-based, counted in runes, 0 if unknown
 }
 
 func (e *UnmarshalTypeError) Error() string {
-	return "toml: cannot unmarshal " + e.Value + " to Go value of type " + e.Type.String()
+	return "toml: " + errorLocation(e.Path, e.Field, e.Line, e.Column) + "cannot unmarshal " + e.Value + " to Go value of type " + e.Type.String()
 }
 
 // An UnmarshalOverflowError describes that a TOML number value overflows
 // specified Go type.
 type UnmarshalOverflowError struct {
-	Value string
-	Type  reflect.Type
+	Value  string
+	Type   reflect.Type
+	Path   string // key path of TOML value, empty for root table
+	Field  string // Go struct field chain, empty if not in struct field
+	Line   int    // 1-based, 0 if unknown
+	Column int    // 1-based, counted in runes, 0 if unknown
 }
 
 func (e *UnmarshalOverflowError) Error() string {
-	return "toml: " + e.Value + " overflow Go value of type " + e.Type.String()
+	return "toml: " + errorLocation(e.Path, e.Field, e.Line, e.Column) + e.Value + " overflow Go value of type " + e.Type.String()
+}
+
+// An UnmarshalerError describes an error returned from UnmarshalTOML
+// method of an Unmarshaler.
+type UnmarshalerError struct {
+	Type   reflect.Type
+	Err    error
+	Path   string // key path of TOML value, empty for root table
+	Field  string // Go struct field chain, empty if not in struct field
+	Line   int    // 1-based, 0 if unknown
+	Column int    // 1-based, counted in runes, 0 if unknown
+}
+
+func (e *UnmarshalerError) Error() string {
+	return "toml: " + errorLocation(e.Path, e.Field, e.Line, e.Column) + "error calling UnmarshalTOML for Go value of type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error returned from UnmarshalTOML.
+func (e *UnmarshalerError) Unwrap() error {
+	return e.Err
+}
+
+// A DefaultValueError describes that value in "default" tag of a struct
+// field is not a valid TOML value, or can't be decoded into the field.
+type DefaultValueError struct {
+	Value  string // text in default tag
+	Type   reflect.Type
+	Err    error
+	Path   string // key path of absent TOML value
+	Field  string // Go struct field chain
+	Line   int    // 1-based, 0 if unknown
+	Column int    // 1-based, counted in runes, 0 if unknown
+}
+
+func (e *DefaultValueError) Error() string {
+	return "toml: " + errorLocation(e.Path, e.Field, e.Line, e.Column) + "invalid default value " + strconv.Quote(e.Value) + " for Go value of type " + e.Type.String() + ": " + strings.TrimPrefix(e.Err.Error(), "toml: ")
+}
+
+// Unwrap returns the syntax error in default value, or the error decoding
+// it, say, *UnmarshalTypeError.
+func (e *DefaultValueError) Unwrap() error {
+	return e.Err
+}
+
+// errorLocation formats known parts of location of unmarshal error, for
+// example, "line 3, column 9, key database.ports[2], field Database.Ports: ".
+func errorLocation(path, field string, line, column int) string {
+	var parts []string
+	if line != 0 {
+		parts = append(parts, fmt.Sprintf("line %d, column %d", line, column))
+	}
+	if path != "" {
+		parts = append(parts, "key "+path)
+	}
+	if field != "" {
+		parts = append(parts, "field "+field)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, ", ") + ": "
+}
+
+// UnmarshalErrors describes type, overflow and Unmarshaler errors
+// collected in decoding. It is reported only if Decoder.CollectErrors was called.
+type UnmarshalErrors struct {
+	Errors []error
+}
+
+func (e *UnmarshalErrors) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		return "toml: no errors"
+	case 1:
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0], len(e.Errors)-1)
+}
+
+// Is reports whether any collected error matches target, see errors.Is.
+func (e *UnmarshalErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds first collected error that matches target, see errors.As.
+func (e *UnmarshalErrors) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// An UnknownKey describes a TOML key which has no matching struct field.
+type UnknownKey struct {
+	Path string
+	Line int
+}
+
+type unknownKeys []UnknownKey
+
+func (s unknownKeys) Len() int      { return len(s) }
+func (s unknownKeys) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s unknownKeys) Less(i, j int) bool {
+	if s[i].Line != s[j].Line {
+		return s[i].Line < s[j].Line
+	}
+	return s[i].Path < s[j].Path
+}
+
+// An UnknownFieldError describes TOML keys which have no matching struct
+// fields. It is reported only if Decoder.DisallowUnknownFields was called.
+type UnknownFieldError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownFieldError) Error() string {
+	keys := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		keys[i] = fmt.Sprintf("%s (line %d)", key.Path, key.Line)
+	}
+	return "toml: unknown keys: " + strings.Join(keys, ", ")
+}
+
+// A RequiredFieldError describes absent TOML keys of struct fields which
+// are tagged with required option.
+type RequiredFieldError struct {
+	Keys []string
+}
+
+func (e *RequiredFieldError) Error() string {
+	return "toml: missing required keys: " + strings.Join(e.Keys, ", ")
 }
 
 func indirectType(t reflect.Type) reflect.Type {
@@ -59,7 +253,20 @@ func indirectType(t reflect.Type) reflect.Type {
 	return t
 }
 
-func indirectValue(v reflect.Value) (encoding.TextUnmarshaler, reflect.Value) {
+// Unmarshaler is the interface implemented by types that can unmarshal
+// TOML value of themselves. The value passed to UnmarshalTOML is of the
+// same form as what Unmarshal stores in an empty interface value, that
+// is, bool, int64, *big.Int, float64, *big.Float, Number, string,
+// time.Time, LocalDate, LocalTime, LocalDateTime, []interface{} or
+// map[string]interface{}.
+type Unmarshaler interface {
+	UnmarshalTOML(v interface{}) error
+}
+
+// indirectValue walks down v allocating pointers as needed, until it gets
+// to a non-pointer or an Unmarshaler. If an Unmarshaler is encountered,
+// the returned reflect.Value is the pointer implementing it.
+func indirectValue(v reflect.Value) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
 	var u encoding.TextUnmarshaler
 	for {
 		if v.Kind() == reflect.Interface && !v.IsNil() {
@@ -76,6 +283,9 @@ func indirectValue(v reflect.Value) (encoding.TextUnmarshaler, reflect.Value) {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
 		if v.NumMethod() > 0 {
+			if i, ok := v.Interface().(Unmarshaler); ok {
+				return i, nil, v
+			}
 			// TOML has native Datetime support, while time.Time implements
 			// encoding.TextUnmarshaler. For native Datetime, we need settable
 			// time.Time struct, so continue here.
@@ -85,13 +295,36 @@ func indirectValue(v reflect.Value) (encoding.TextUnmarshaler, reflect.Value) {
 		}
 		v = v.Elem()
 	}
-	return u, v
+	return nil, u, v
 }
 
-func findField(t *types.Table, field *reflect.StructField, tagname string) (string, types.Value) {
+// namedKeys returns keys of t indexed by names converted by naming strategy.
+// If keys are converted to same name, the first defined one wins.
+func (d *decodeState) namedKeys(t *types.Table) map[string]string {
+	keys := make(map[string]string, len(t.Keys))
+	for _, key := range t.Keys {
+		name := d.naming(key)
+		if _, ok := keys[name]; !ok {
+			keys[name] = key
+		}
+	}
+	return keys
+}
+
+func (d *decodeState) findField(t *types.Table, keys map[string]string, field *reflect.StructField, tagname string) (string, types.Value) {
 	if tagname != "" {
 		return tagname, t.Elems[tagname]
 	}
+	if d.naming != nil {
+		name := d.naming(field.Name)
+		if value, ok := t.Elems[name]; ok {
+			return name, value
+		}
+		if key, ok := keys[name]; ok {
+			return key, t.Elems[key]
+		}
+		return name, nil
+	}
 	if value, ok := t.Elems[field.Name]; ok {
 		return field.Name, value
 	}
@@ -99,7 +332,7 @@ func findField(t *types.Table, field *reflect.StructField, tagname string) (stri
 	return lowerName, t.Elems[lowerName]
 }
 
-func unmarshalBoolean(b bool, v reflect.Value) {
+func (d *decodeState) unmarshalBoolean(b bool, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Bool:
 		v.SetBool(b)
@@ -110,11 +343,11 @@ func unmarshalBoolean(b bool, v reflect.Value) {
 		}
 		fallthrough
 	default:
-		panic(&UnmarshalTypeError{"boolean " + strconv.FormatBool(b), v.Type()})
+		panic(&UnmarshalTypeError{Value: "boolean " + strconv.FormatBool(b), Type: v.Type()})
 	}
 }
 
-func unmarshalQuoted(s string, v reflect.Value) {
+func (d *decodeState) unmarshalQuoted(s string, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Bool:
 		b, err := strconv.ParseBool(s)
@@ -154,14 +387,14 @@ func unmarshalQuoted(s string, v reflect.Value) {
 	}
 	return
 overflowError:
-	panic(&UnmarshalOverflowError{"string " + strconv.Quote(s), v.Type()})
+	panic(&UnmarshalOverflowError{Value: "string " + strconv.Quote(s), Type: v.Type()})
 }
 
-func unmarshalString(s string, v reflect.Value, options tagOptions) {
+func (d *decodeState) unmarshalString(s string, v reflect.Value, options tagOptions) {
 	if v.Kind() != reflect.Ptr && v.CanAddr() {
 		v = v.Addr()
 	}
-	u, v := indirectValue(v)
+	_, u, v := indirectValue(v)
 	if u != nil {
 		err := u.UnmarshalText([]byte(s))
 		if err != nil {
@@ -175,6 +408,14 @@ func unmarshalString(s string, v reflect.Value, options tagOptions) {
 		}
 		return
 	}
+	if v.Type() == durationType {
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			goto typeError
+		}
+		v.SetInt(int64(duration))
+		return
+	}
 	switch v.Kind() {
 	case reflect.String:
 		if options.Has("string") {
@@ -203,7 +444,7 @@ func unmarshalString(s string, v reflect.Value, options tagOptions) {
 		if !options.Has("string") {
 			goto typeError
 		}
-		unmarshalQuoted(s, v)
+		d.unmarshalQuoted(s, v)
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			goto typeError
@@ -214,70 +455,281 @@ func unmarshalString(s string, v reflect.Value, options tagOptions) {
 	}
 	return
 typeError:
-	panic(&UnmarshalTypeError{fmt.Sprintf("string: %q", s), v.Type()})
+	panic(&UnmarshalTypeError{Value: fmt.Sprintf("string: %q", s), Type: v.Type()})
 }
 
-func unmarshalDatetime(t time.Time, v reflect.Value) {
+func (d *decodeState) unmarshalDatetime(t time.Time, v reflect.Value) {
 	if t.IsZero() {
 		t = time.Time{}
 	}
 	if !reflect.TypeOf(t).ConvertibleTo(v.Type()) {
-		panic(&UnmarshalTypeError{"datetime " + t.Format(time.RFC3339Nano), v.Type()})
+		panic(&UnmarshalTypeError{Value: "datetime " + t.Format(time.RFC3339Nano), Type: v.Type()})
 	}
 	v.Set(reflect.ValueOf(t).Convert(v.Type()))
 }
 
-func unmarshalFloat(f float64, v reflect.Value) {
+func (d *decodeState) unmarshalLocalValue(kind string, value fmt.Stringer, t time.Time, v reflect.Value) {
+	switch {
+	case v.Type() == reflect.TypeOf(value):
+		v.Set(reflect.ValueOf(value))
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		v.Set(reflect.ValueOf(value))
+	case datetimeType.ConvertibleTo(v.Type()):
+		v.Set(reflect.ValueOf(t).Convert(v.Type()))
+	default:
+		panic(&UnmarshalTypeError{Value: kind + " " + value.String(), Type: v.Type()})
+	}
+}
+
+func (d *decodeState) unmarshalLocalDate(date LocalDate, v reflect.Value) {
+	d.unmarshalLocalValue("local date", date, date.In(time.Local), v)
+}
+
+func (d *decodeState) unmarshalLocalTime(t LocalTime, v reflect.Value) {
+	date := LocalDate{Month: time.January, Day: 1}
+	d.unmarshalLocalValue("local time", t, LocalDateTime{date, t}.In(time.Local), v)
+}
+
+func (d *decodeState) unmarshalLocalDateTime(dt LocalDateTime, v reflect.Value) {
+	d.unmarshalLocalValue("local datetime", dt, dt.In(time.Local), v)
+}
+
+var (
+	numberType   = reflect.TypeOf(Number(""))
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+func bigInt(v reflect.Value) *big.Int {
+	return v.Addr().Interface().(*big.Int)
+}
+
+func bigFloat(v reflect.Value) *big.Float {
+	return v.Addr().Interface().(*big.Float)
+}
+
+// parseBigFloat parses TOML number text s into z. If z has zero precision,
+// it gets precision enough to keep all digits in s. It reports whether s is
+// a number z can hold, NaN is not.
+func parseBigFloat(z *big.Float, s string) bool {
+	if z.Prec() == 0 {
+		// Four bits per character are more than a digit takes in any base
+		// except hexadecimal, which takes exactly four.
+		prec := uint(64)
+		if n := uint(4 * len(s)); n > prec {
+			prec = n
+		}
+		z.SetPrec(prec)
+	}
+	_, _, err := z.Parse(s, 0)
+	return err == nil
+}
+
+func (d *decodeState) unmarshalFloat(tv types.Float, v reflect.Value) {
+	f := tv.Value
+	text := strconv.FormatFloat(f, 'g', -1, 64)
+	if tv.Overflow {
+		text = tv.Text
+	}
+	switch v.Type() {
+	case numberType:
+		v.SetString(tv.Text)
+		return
+	case bigFloatType:
+		if parseBigFloat(bigFloat(v), tv.Text) {
+			return
+		}
+	}
 	switch v.Kind() {
 	case reflect.Float32, reflect.Float64:
-		if v.OverflowFloat(f) {
-			panic(&UnmarshalOverflowError{"float " + strconv.FormatFloat(f, 'g', -1, 64), v.Type()})
+		if tv.Overflow || v.OverflowFloat(f) {
+			panic(&UnmarshalOverflowError{Value: "float " + text, Type: v.Type()})
 		}
 		v.SetFloat(f)
 	case reflect.Interface:
 		if v.NumMethod() == 0 {
-			v.Set(reflect.ValueOf(f))
+			v.Set(reflect.ValueOf(d.valueInterface(tv)))
 			return
 		}
 		fallthrough
 	default:
-		panic(&UnmarshalTypeError{"float " + strconv.FormatFloat(f, 'g', -1, 64), v.Type()})
+		panic(&UnmarshalTypeError{Value: "float " + text, Type: v.Type()})
+	}
+}
+
+func (d *decodeState) unmarshalDuration(i int64, v reflect.Value) {
+	unit := d.durationUnit
+	if unit == 0 {
+		unit = time.Nanosecond
+	}
+	duration := time.Duration(i) * unit
+	if duration/unit != time.Duration(i) {
+		panic(&UnmarshalOverflowError{Value: "integer " + strconv.FormatInt(i, 10), Type: v.Type()})
 	}
+	v.SetInt(int64(duration))
 }
 
-func unmarshalInteger(i int64, v reflect.Value) {
+func (d *decodeState) unmarshalInteger(tv types.Integer, v reflect.Value) {
+	i := tv.Value
+	switch v.Type() {
+	case durationType:
+		d.unmarshalDuration(i, v)
+		return
+	case numberType:
+		v.SetString(tv.Text)
+		return
+	case bigIntType:
+		bigInt(v).SetInt64(i)
+		return
+	case bigFloatType:
+		bigFloat(v).SetInt64(i)
+		return
+	}
 	switch v.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if v.OverflowInt(i) {
-			panic(&UnmarshalOverflowError{"integer " + strconv.FormatInt(i, 10), v.Type()})
+			panic(&UnmarshalOverflowError{Value: "integer " + strconv.FormatInt(i, 10), Type: v.Type()})
 		}
 		v.SetInt(i)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if i < 0 {
-			panic(&UnmarshalOverflowError{"integer " + strconv.FormatInt(i, 10), v.Type()})
+			panic(&UnmarshalOverflowError{Value: "integer " + strconv.FormatInt(i, 10), Type: v.Type()})
 		}
 		u := uint64(i)
 		if v.OverflowUint(u) {
-			panic(&UnmarshalOverflowError{"integer " + strconv.FormatUint(u, 10), v.Type()})
+			panic(&UnmarshalOverflowError{Value: "integer " + strconv.FormatUint(u, 10), Type: v.Type()})
 		}
 		v.SetUint(u)
 	case reflect.Interface:
 		if v.NumMethod() == 0 {
-			v.Set(reflect.ValueOf(i))
+			v.Set(reflect.ValueOf(d.valueInterface(tv)))
 			return
 		}
 		fallthrough
 	default:
-		panic(&UnmarshalTypeError{"integer " + strconv.FormatInt(i, 10), v.Type()})
+		panic(&UnmarshalTypeError{Value: "integer " + strconv.FormatInt(i, 10), Type: v.Type()})
+	}
+}
+
+// unmarshalBigInteger stores integer in literal text s, which is out of
+// range of int64, in v.
+func (d *decodeState) unmarshalBigInteger(s string, v reflect.Value) {
+	i, _ := new(big.Int).SetString(s, 0)
+	switch v.Type() {
+	case numberType:
+		v.SetString(s)
+		return
+	case bigIntType:
+		bigInt(v).Set(i)
+		return
+	case bigFloatType:
+		bigFloat(v).SetInt(i)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		panic(&UnmarshalOverflowError{Value: "integer " + i.String(), Type: v.Type()})
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !i.IsUint64() || v.OverflowUint(i.Uint64()) {
+			panic(&UnmarshalOverflowError{Value: "integer " + i.String(), Type: v.Type()})
+		}
+		v.SetUint(i.Uint64())
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(d.valueInterface(types.BigInteger(s))))
+			return
+		}
+		fallthrough
+	default:
+		panic(&UnmarshalTypeError{Value: "integer " + i.String(), Type: v.Type()})
 	}
 }
 
 var emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 
-func unmarshalMap(t *types.Table, v reflect.Value) {
+func (d *decodeState) valueInterface(v types.Value) interface{} {
+	switch v := v.(type) {
+	case types.Boolean:
+		return bool(v)
+	case types.Integer:
+		if d.useNumber {
+			return Number(v.Text)
+		}
+		return v.Value
+	case types.BigInteger:
+		if d.useNumber {
+			return Number(v)
+		}
+		i, _ := new(big.Int).SetString(string(v), 0)
+		return i
+	case types.Float:
+		if d.useNumber {
+			return Number(v.Text)
+		}
+		if v.Overflow {
+			f := new(big.Float)
+			parseBigFloat(f, v.Text)
+			return f
+		}
+		return v.Value
+	case types.String:
+		return string(v)
+	case types.Datetime:
+		return time.Time(v)
+	case types.LocalDate:
+		return LocalDateOf(time.Time(v))
+	case types.LocalTime:
+		return LocalTimeOf(time.Time(v))
+	case types.LocalDatetime:
+		return LocalDateTimeOf(time.Time(v))
+	case *types.Array:
+		return d.arrayInterface(v)
+	case *types.Table:
+		return d.tableInterface(v)
+	}
+	return nil
+}
+
+func (d *decodeState) arrayInterface(a *types.Array) []interface{} {
+	s := make([]interface{}, len(a.Elems))
+	for i, value := range a.Elems {
+		s[i] = d.valueInterface(value)
+	}
+	return s
+}
+
+func (d *decodeState) tableInterface(t *types.Table) map[string]interface{} {
+	m := make(map[string]interface{}, len(t.Elems))
+	for key, value := range t.Elems {
+		m[key] = d.valueInterface(value)
+	}
+	return m
+}
+
+var (
+	valueType = reflect.TypeOf((*Value)(nil)).Elem()
+	tableType = reflect.TypeOf(Table{})
+	arrayType = reflect.TypeOf(Array{})
+)
+
+// unmarshalTree stores tv as document tree value in v, which is Value,
+// Table or Array.
+func (d *decodeState) unmarshalTree(path string, tv types.Value, v reflect.Value) {
+	value := reflect.ValueOf(treeValue(tv))
+	if v.Type() != valueType {
+		if value.Kind() != reflect.Ptr || value.Type().Elem() != v.Type() {
+			panic(&UnmarshalTypeError{Value: tv.Type(), Type: v.Type()})
+		}
+		value = value.Elem()
+	}
+	d.markDecoded(path, tv)
+	v.Set(value)
+}
+
+func (d *decodeState) unmarshalMap(path string, t *types.Table, v reflect.Value) {
 	keyType := v.Type().Key()
 	if keyType.Kind() != reflect.String {
-		panic(&UnmarshalTypeError{"table", v.Type()})
+		panic(&UnmarshalTypeError{Value: "table", Type: v.Type()})
 	}
 	m := reflect.MakeMap(v.Type())
 	elemType := v.Type().Elem()
@@ -285,15 +737,19 @@ func unmarshalMap(t *types.Table, v reflect.Value) {
 	elemValue := reflect.New(elemType).Elem()
 	for key, value := range t.Elems {
 		elemValue.Set(elemZero)
-		unmarshalValue(value, elemValue, nil)
+		d.unmarshalValue(combineKeyPath(path, key), value, elemValue, nil)
 		m.SetMapIndex(reflect.ValueOf(key).Convert(keyType), elemValue)
 	}
 	v.Set(m)
 }
 
-func unmarshalStructNested(t *types.Table, v reflect.Value, matchs map[string]struct{}) {
-	_, v = indirectValue(v)
+func (d *decodeState) unmarshalStructNested(path string, t *types.Table, v reflect.Value, matchs map[string]struct{}) {
+	v = reflect.Indirect(v)
 	vType := v.Type()
+	var keys map[string]string
+	if d.naming != nil {
+		keys = d.namedKeys(t)
+	}
 	for i := 0; i < v.NumField(); i++ {
 		field := vType.Field(i)
 		name, options := parseTag(field.Tag.Get("toml"))
@@ -305,7 +761,7 @@ func unmarshalStructNested(t *types.Table, v reflect.Value, matchs map[string]st
 			fieldValue := v.Field(i)
 			switch field.Type.Kind() {
 			case reflect.Struct:
-				unmarshalStructNested(t, v.Field(i), matchs)
+				d.unmarshalStructNested(path, t, v.Field(i), matchs)
 				continue
 			case reflect.Ptr:
 				if field.Type.Elem().Kind() != reflect.Struct {
@@ -314,12 +770,12 @@ func unmarshalStructNested(t *types.Table, v reflect.Value, matchs map[string]st
 				if fieldValue.IsNil() {
 					fieldNew := reflect.New(field.Type.Elem())
 					n := len(matchs)
-					unmarshalStructNested(t, fieldNew.Elem(), matchs)
+					d.unmarshalStructNested(path, t, fieldNew.Elem(), matchs)
 					if n != len(matchs) {
 						fieldValue.Set(fieldNew)
 					}
 				} else {
-					unmarshalStructNested(t, fieldValue, matchs)
+					d.unmarshalStructNested(path, t, fieldValue, matchs)
 				}
 				continue
 			}
@@ -330,97 +786,234 @@ func unmarshalStructNested(t *types.Table, v reflect.Value, matchs map[string]st
 		if !ast.IsExported(field.Name) {
 			continue
 		}
-		name, value := findField(t, &field, name)
+		name, value := d.findField(t, keys, &field, name)
 		if value == nil {
-			if options.Has("omitempty") {
+			if options.Has("required") {
+				d.missingKeys = append(d.missingKeys, combineKeyPath(path, name))
+			}
+			if options.Has("omitempty") && t != absentTable {
 				v.Field(i).Set(reflect.Zero(field.Type))
 			}
+			parent := d.enterField(field.Name)
+			d.unmarshalDefault(combineKeyPath(path, name), &field, v.Field(i), options)
+			d.field = parent
 			continue
 		}
 		if _, ok := matchs[name]; ok {
 			continue
 		}
-		unmarshalValue(value, v.Field(i), options)
+		parent := d.enterField(field.Name)
+		d.unmarshalValue(combineKeyPath(path, name), value, v.Field(i), options)
+		d.field = parent
 		matchs[name] = struct{}{}
 	}
 }
 
-func unmarshalStruct(t *types.Table, v reflect.Value) {
-	unmarshalStructNested(t, v, make(map[string]struct{}, len(t.Elems)))
+// enterField appends name to Go struct field chain of value being decoded,
+// and returns the chain before, which should be restored after decoding.
+func (d *decodeState) enterField(name string) string {
+	parent := d.field
+	if parent == "" {
+		d.field = name
+	} else {
+		d.field = parent + "." + name
+	}
+	return parent
+}
+
+type defaultKey struct {
+	value   string
+	version Version
+}
+
+type defaultValue struct {
+	value types.Value
+	err   error
+}
+
+// defaultCache caches parsed values of default tags.
+var defaultCache sync.Map // map[defaultKey]defaultValue
+
+func parseDefault(s string, version Version) (types.Value, error) {
+	key := defaultKey{s, version}
+	if v, ok := defaultCache.Load(key); ok {
+		return v.(defaultValue).value, v.(defaultValue).err
+	}
+	var v defaultValue
+	t, err := parse([]byte("default = "+s), version)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			err = perr.Err
+		}
+		v.err = err
+	} else {
+		v.value = t.Elems["default"]
+	}
+	defaultCache.Store(key, v)
+	return v.value, v.err
 }
 
-func unmarshalTable(t *types.Table, v reflect.Value) {
+// absentTable stands for table absent in TOML document, fields of struct
+// decoded from it are untouched except those with default values.
+var absentTable = &types.Table{}
+
+// unmarshalDefault stores default value of field in v, as key of field is
+// absent in TOML document. Default value is written in TOML in "default"
+// tag of field. Struct field without default value gets default values
+// of its fields.
+func (d *decodeState) unmarshalDefault(path string, field *reflect.StructField, v reflect.Value, options tagOptions) {
+	s, ok := field.Tag.Lookup("default")
+	if !ok {
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			d.unmarshalStructNested(path, absentTable, v, make(map[string]struct{}))
+		}
+		return
+	}
+	if d.collectErrors {
+		defer d.collectError()
+	}
+	value, err := parseDefault(s, d.version)
+	if err != nil {
+		d.path = path
+		panic(&DefaultValueError{Value: s, Type: field.Type, Err: err})
+	}
+	// Default values are not decoded from TOML document, errors in
+	// decoding them are errors of default tag.
+	decoded, collectErrors := d.decoded, d.collectErrors
+	d.decoded, d.collectErrors = nil, false
+	defer func() {
+		d.decoded, d.collectErrors = decoded, collectErrors
+		r := recover()
+		if r == nil {
+			return
+		}
+		switch err := r.(type) {
+		case *UnmarshalTypeError, *UnmarshalOverflowError, *UnmarshalerError:
+			d.path = path
+			panic(&DefaultValueError{Value: s, Type: field.Type, Err: err.(error)})
+		}
+		panic(r)
+	}()
+	d.unmarshalValue(path, value, v, options)
+}
+
+func (d *decodeState) unmarshalStruct(path string, t *types.Table, v reflect.Value) {
+	matchs := make(map[string]struct{}, len(t.Elems))
+	d.unmarshalStructNested(path, t, v, matchs)
+	if !d.disallowUnknownFields {
+		return
+	}
+	for key := range t.Elems {
+		if _, ok := matchs[key]; !ok {
+			d.unknownKeys = append(d.unknownKeys, UnknownKey{Path: combineKeyPath(path, key), Line: t.Lines[key]})
+		}
+	}
+}
+
+func (d *decodeState) unmarshalTable(path string, t *types.Table, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Map:
-		unmarshalMap(t, v)
+		d.unmarshalMap(path, t, v)
 	case reflect.Struct:
-		unmarshalStruct(t, v)
+		d.unmarshalStruct(path, t, v)
 	case reflect.Interface:
 		if v.NumMethod() == 0 {
-			v.Set(reflect.ValueOf(t.Interface()))
+			d.markDecoded(path, t)
+			v.Set(reflect.ValueOf(d.tableInterface(t)))
 			return
 		}
 		fallthrough
 	default:
-		panic(&UnmarshalTypeError{"table", v.Type()})
+		panic(&UnmarshalTypeError{Value: "table", Type: v.Type()})
 	}
 }
 
-func unmarshalSlice(a *types.Array, v reflect.Value) {
+func (d *decodeState) unmarshalSlice(path string, a *types.Array, v reflect.Value) {
 	n := len(a.Elems)
 	slice := reflect.MakeSlice(v.Type(), n, n)
 	for i, value := range a.Elems {
-		unmarshalValue(value, slice.Index(i), nil)
+		d.unmarshalValue(combineIndexPath(path, i), value, slice.Index(i), nil)
 	}
 	v.Set(slice)
 }
 
-func unmarshalGoArray(a *types.Array, v reflect.Value) {
+func (d *decodeState) unmarshalGoArray(path string, a *types.Array, v reflect.Value) {
 	if len(a.Elems) != v.Type().Len() {
-		panic(&UnmarshalTypeError{fmt.Sprintf("[%d]array", len(a.Elems)), v.Type()})
-	}
-	if v.IsNil() {
-		v.Set(reflect.Zero(v.Type()))
+		panic(&UnmarshalTypeError{Value: fmt.Sprintf("[%d]array", len(a.Elems)), Type: v.Type()})
 	}
 	for i, value := range a.Elems {
-		unmarshalValue(value, v.Index(i), nil)
+		d.unmarshalValue(combineIndexPath(path, i), value, v.Index(i), nil)
 	}
 }
 
-func unmarshalArray(a *types.Array, v reflect.Value) {
+func (d *decodeState) unmarshalArray(path string, a *types.Array, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Array:
-		unmarshalGoArray(a, v)
+		d.unmarshalGoArray(path, a, v)
 	case reflect.Slice:
-		unmarshalSlice(a, v)
+		d.unmarshalSlice(path, a, v)
 	case reflect.Interface:
 		if v.NumMethod() == 0 {
-			v.Set(reflect.ValueOf(a.Interface()))
+			d.markDecoded(path, a)
+			v.Set(reflect.ValueOf(d.arrayInterface(a)))
 			return
 		}
 		fallthrough
 	default:
-		panic(&UnmarshalTypeError{"array", v.Type()})
+		panic(&UnmarshalTypeError{Value: "array", Type: v.Type()})
 	}
 }
 
-func unmarshalValue(tv types.Value, rv reflect.Value, options tagOptions) {
-	_, rv = indirectValue(rv)
+func (d *decodeState) unmarshalValue(path string, tv types.Value, rv reflect.Value, options tagOptions) {
+	if d.collectErrors {
+		defer d.collectError()
+	}
+	d.path = path
+	if d.decoded != nil {
+		d.decoded[path] = struct{}{}
+	}
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() {
+		rv = rv.Addr()
+	}
+	u, _, rv := indirectValue(rv)
+	if u != nil {
+		d.markDecoded(path, tv)
+		if err := u.UnmarshalTOML(d.valueInterface(tv)); err != nil {
+			panic(&UnmarshalerError{Type: rv.Type().Elem(), Err: err})
+		}
+		return
+	}
+	switch rv.Type() {
+	case valueType, tableType, arrayType:
+		d.unmarshalTree(path, tv, rv)
+		return
+	}
 	switch tv := tv.(type) {
 	case types.Boolean:
-		unmarshalBoolean(bool(tv), rv)
+		d.unmarshalBoolean(bool(tv), rv)
 	case types.Float:
-		unmarshalFloat(float64(tv), rv)
+		d.unmarshalFloat(tv, rv)
 	case types.String:
-		unmarshalString(string(tv), rv, options)
+		d.unmarshalString(string(tv), rv, options)
 	case types.Integer:
-		unmarshalInteger(int64(tv), rv)
+		d.unmarshalInteger(tv, rv)
+	case types.BigInteger:
+		d.unmarshalBigInteger(string(tv), rv)
 	case types.Datetime:
-		unmarshalDatetime(time.Time(tv), rv)
+		d.unmarshalDatetime(time.Time(tv), rv)
+	case types.LocalDate:
+		d.unmarshalLocalDate(LocalDateOf(time.Time(tv)), rv)
+	case types.LocalTime:
+		d.unmarshalLocalTime(LocalTimeOf(time.Time(tv)), rv)
+	case types.LocalDatetime:
+		d.unmarshalLocalDateTime(LocalDateTimeOf(time.Time(tv)), rv)
 	case *types.Array:
-		unmarshalArray(tv, rv)
+		d.unmarshalArray(path, tv, rv)
 	case *types.Table:
-		unmarshalTable(tv, rv)
+		d.unmarshalTable(path, tv, rv)
 	}
 }
 
@@ -437,12 +1030,13 @@ func catchError(errp *error) {
 	}
 }
 
-// Unmarshal parses TOML data and stores the result in the value pointed
-// by v.
+// Unmarshal parses TOML data in DefaultVersion of TOML specification and
+// stores the result in the value pointed by v.
 //
 // To unmarshal TOML into a struct, Unmarshal uses TOML tagged name to
 // find matching item in TOML table. Field name and its lower case will
-// got tried in sequence if TOML tagged name is absent. Options can be
+// got tried in sequence if TOML tagged name is absent, see also
+// Decoder.SetNamingStrategy. Options can be
 // specified after tag name separated by comma. Examples:
 //
 //   // Field is ignored by this package.
@@ -459,33 +1053,302 @@ func catchError(errp *error) {
 //   // this field can be unmarshalled from TOML string.
 //   Field int `toml:",string"
 //
+//   // "Field" and "field" will be used to find key in TOML table, and
+//   // Unmarshal reports RequiredFieldError if it is absent.
+//   Field int `toml:",required"`
+//
+//   // "port" will be used to find key in TOML table, and if it is
+//   // absent, TOML value in default tag will be stored in this field.
+//   Field int `toml:"port" default:"8080"`
+//
+// Default values are written in TOML, so strings must be quoted, say,
+// `default:"'localhost'"`, and arrays of tables can be written as arrays of
+// inline tables. If a table is absent, fields of its struct get default
+// values too, and required fields of its struct are reported absent unless
+// the struct is referenced by a nil pointer. Invalid default values are
+// reported in DefaultValueError.
+//
+// If a value implements Unmarshaler, Unmarshal calls its UnmarshalTOML
+// method with TOML value, errors returned are reported in UnmarshalerError.
+// If a value implements encoding.TextUnmarshaler and TOML value is a
+// string, Unmarshal calls its UnmarshalText method.
+//
 // To unmarshal TOML into an interface value, Unmarshal stores TOML
 // value in following types:
 //
 //   bool, for TOML Boolean
 //   int64, for TOML Integer
+//   *big.Int, for TOML Integer out of range of int64
 //   float64, for TOML Float
+//   *big.Float, for TOML Float out of range of float64
 //   string, for TOML String
 //   time.Time, for TOML Datetime
+//   LocalDate, for TOML Local Date
+//   LocalTime, for TOML Local Time
+//   LocalDateTime, for TOML Local Date-Time
 //   []interface{}, for TOML Array
 //   map[string]interface{}, for TOML Table
 //
+// TOML Local Date, Local Time and Local Date-Time can also be stored in
+// time.Time, they are interpreted as in time.Local.
+//
+// TOML integers and floats can also be stored in Number, *big.Int and
+// *big.Float, floats stored in *big.Int are type errors. Number keeps
+// literal text of TOML number, and *big.Float with zero precision gets
+// precision enough to keep all its digits. Integers up to math.MaxUint64
+// can be stored in uint64. See Decoder.UseNumber to store numbers in
+// interface values as Number.
+//
+// To unmarshal TOML into time.Duration, Unmarshal accepts TOML string in
+// format of time.ParseDuration, say, "1m30s", or TOML integer in
+// nanoseconds, see Decoder.SetDurationUnit.
+//
+// To unmarshal TOML into Value, *Table or *Array, Unmarshal stores TOML
+// value as document tree, see Parse.
+//
 // There is no guarantee that origin data in Go value will be preserved
 // after a failure or success Unmarshal().
-func Unmarshal(data []byte, v interface{}) (err error) {
-	defer catchError(&err)
+func Unmarshal(data []byte, v interface{}) error {
+	d := &decodeState{version: DefaultVersion}
+	return d.unmarshal(data, v)
+}
 
-	t, err := parse(data)
+// UnmarshalMetaData is like Unmarshal, but also returns MetaData which
+// describes keys defined in data. MetaData is nil if data is not a valid
+// TOML document.
+func UnmarshalMetaData(data []byte, v interface{}) (*MetaData, error) {
+	d := &decodeState{version: DefaultVersion}
+	return d.unmarshalMetaData(data, v)
+}
+
+// UnmarshalVersion is like Unmarshal, but parses data in specified version
+// of TOML specification. It is a shorthand of Decoder with SetVersion.
+func UnmarshalVersion(data []byte, v interface{}, version Version) error {
+	dec := NewDecoder(nil)
+	dec.SetVersion(version)
+	return dec.decodeState().unmarshal(data, v)
+}
+
+func (d *decodeState) unmarshal(data []byte, v interface{}) error {
+	t, err := parse(data, d.version)
 	if err != nil {
 		return err
 	}
+	return d.decode(string(data), t, v)
+}
+
+func (d *decodeState) unmarshalMetaData(data []byte, v interface{}) (*MetaData, error) {
+	t, err := parse(data, d.version)
+	if err != nil {
+		return nil, err
+	}
+	src := string(data)
+	d.decoded = make(map[string]struct{})
+	err = d.decode(src, t, v)
+	return newMetaData(src, t, d.decoded), err
+}
+
+func (d *decodeState) decode(src string, t *types.Table, v interface{}) (err error) {
+	d.src, d.root = src, t
+	defer d.locateError(&err)
+	defer catchError(&err)
 
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
 
-	_, rv = indirectValue(rv)
-	unmarshalTable(t, rv)
+	d.unmarshalValue("", t, rv, nil)
+	if len(d.missingKeys) != 0 {
+		sort.Strings(d.missingKeys)
+		err := &RequiredFieldError{Keys: d.missingKeys}
+		if !d.collectErrors {
+			return err
+		}
+		d.errors = append(d.errors, err)
+	}
+	if len(d.unknownKeys) != 0 {
+		sort.Sort(unknownKeys(d.unknownKeys))
+		err := &UnknownFieldError{Keys: d.unknownKeys}
+		if !d.collectErrors {
+			return err
+		}
+		d.errors = append(d.errors, err)
+	}
+	if len(d.errors) != 0 {
+		return &UnmarshalErrors{Errors: d.errors}
+	}
 	return nil
 }
+
+// collectError recovers from type or overflow error in value being decoded
+// and collects it, so decoding continues with other values.
+func (d *decodeState) collectError() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if err, ok := r.(error); ok && d.locate(err) {
+		d.errors = append(d.errors, err)
+		return
+	}
+	panic(r)
+}
+
+func (d *decodeState) locateError(errp *error) {
+	d.locate(*errp)
+}
+
+// locate fills key path, Go struct field chain and source position of
+// value being decoded in unmarshal error. It reports whether err is a
+// type, overflow, Unmarshaler or default value error.
+func (d *decodeState) locate(err error) bool {
+	switch err := err.(type) {
+	case *UnmarshalTypeError:
+		err.Path, err.Field = d.path, d.field
+		err.Line, err.Column = d.position()
+	case *UnmarshalOverflowError:
+		err.Path, err.Field = d.path, d.field
+		err.Line, err.Column = d.position()
+	case *UnmarshalerError:
+		err.Path, err.Field = d.path, d.field
+		err.Line, err.Column = d.position()
+	case *DefaultValueError:
+		err.Path, err.Field = d.path, d.field
+		err.Line, err.Column = d.position()
+	default:
+		return false
+	}
+	return true
+}
+
+// position returns where value being decoded was written, or where its key
+// was defined if it was not written in place.
+func (d *decodeState) position() (line, column int) {
+	if d.meta == nil {
+		d.meta = newMetaData(d.src, d.root, nil)
+	}
+	span, ok := d.meta.ValuePosition(d.path)
+	if !ok {
+		span, ok = d.meta.KeyPosition(d.path)
+	}
+	if !ok {
+		return 0, 0
+	}
+	return span.Start.Line, span.Start.Column
+}
+
+// A Decoder reads and decodes TOML document from an input stream.
+type Decoder struct {
+	r       io.Reader
+	version Version
+
+	disallowUnknownFields bool
+	collectErrors         bool
+	naming                NamingStrategy
+	durationUnit          time.Duration
+	useNumber             bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, version: DefaultVersion}
+}
+
+// SetVersion sets version of TOML specification the decoder conforms to.
+func (dec *Decoder) SetVersion(v Version) {
+	dec.version = v.normalize()
+}
+
+// DisallowUnknownFields causes the Decoder to return an UnknownFieldError
+// when the destination is a struct and the input contains keys which do
+// not match any non-ignored, exported fields in the destination.
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.disallowUnknownFields = true
+}
+
+// CollectErrors causes the Decoder to continue decoding other values after
+// type, overflow or Unmarshaler errors, and return all of them in an
+// UnmarshalErrors.
+// Values which failed to decode are left as they were at failure.
+func (dec *Decoder) CollectErrors() {
+	dec.collectErrors = true
+}
+
+// SetNamingStrategy sets strategy to find TOML keys of struct fields without
+// tagged names. Nil strategy restores the default, which tries field name
+// and its lower case in sequence.
+func (dec *Decoder) SetNamingStrategy(naming NamingStrategy) {
+	dec.naming = naming
+}
+
+// SetDurationUnit sets unit of TOML integers decoded into time.Duration.
+// Integers are decoded in nanoseconds by default.
+func (dec *Decoder) SetDurationUnit(unit time.Duration) {
+	dec.durationUnit = unit
+}
+
+// UseNumber causes the Decoder to unmarshal TOML integers and floats into
+// an interface{} as a Number instead of as an int64, *big.Int, float64 or
+// *big.Float.
+func (dec *Decoder) UseNumber() {
+	dec.useNumber = true
+}
+
+// Decode reads TOML document from its input till EOF and stores the
+// result in the value pointed by v.
+//
+// See the documentation for Unmarshal for details about the conversion
+// of TOML into Go value.
+func (dec *Decoder) Decode(v interface{}) error {
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return err
+	}
+	return dec.decodeState().unmarshal(data, v)
+}
+
+// DecodeMetaData is like Decode, but also returns MetaData which describes
+// keys defined in TOML document. MetaData is nil if input is not a valid
+// TOML document.
+func (dec *Decoder) DecodeMetaData(v interface{}) (*MetaData, error) {
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return nil, err
+	}
+	return dec.decodeState().unmarshalMetaData(data, v)
+}
+
+// ParseAll reads TOML document from its input till EOF and parses it in
+// version of the decoder, continuing after syntax errors. See the
+// package-level ParseAll for details.
+func (dec *Decoder) ParseAll() (*Table, error) {
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseAll(data, dec.version)
+	return treeTable(root), err
+}
+
+// ParseDocument reads TOML document from its input till EOF and parses it
+// in version of the decoder as Document. Edits of the document are encoded
+// in the same version.
+func (dec *Decoder) ParseDocument() (*Document, error) {
+	data, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(data, dec.version)
+}
+
+func (dec *Decoder) decodeState() *decodeState {
+	return &decodeState{
+		version:               dec.version,
+		disallowUnknownFields: dec.disallowUnknownFields,
+		collectErrors:         dec.collectErrors,
+		naming:                dec.naming,
+		durationUnit:          dec.durationUnit,
+		useNumber:             dec.useNumber,
+	}
+}
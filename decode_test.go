@@ -1,7 +1,13 @@
 package toml_test
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,6 +65,70 @@ type Datetime struct {
 	T time.Time
 }
 
+type LocalDatetimes struct {
+	Date     toml.LocalDate
+	Time     toml.LocalTime
+	DateTime toml.LocalDateTime
+	T        time.Time
+}
+
+// Seconds unmarshals from integer seconds or duration string.
+type Seconds time.Duration
+
+func (s *Seconds) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case int64:
+		*s = Seconds(time.Duration(v) * time.Second)
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*s = Seconds(d)
+	default:
+		return fmt.Errorf("invalid duration: %v", v)
+	}
+	return nil
+}
+
+func (s Seconds) MarshalTOML() (interface{}, error) {
+	return time.Duration(s).String(), nil
+}
+
+// StringSet marshals to and unmarshals from TOML array of strings.
+type StringSet map[string]struct{}
+
+func (s *StringSet) UnmarshalTOML(v interface{}) error {
+	a, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("expect array, got %T", v)
+	}
+	*s = make(StringSet, len(a))
+	for _, elem := range a {
+		str, ok := elem.(string)
+		if !ok {
+			return fmt.Errorf("expect string, got %T", elem)
+		}
+		(*s)[str] = struct{}{}
+	}
+	return nil
+}
+
+func (s StringSet) MarshalTOML() (interface{}, error) {
+	a := make([]string, 0, len(s))
+	for str := range s {
+		a = append(a, str)
+	}
+	sort.Strings(a)
+	return a, nil
+}
+
+type Unmarshalers struct {
+	Timeout  Seconds
+	Interval *Seconds
+	Tags     StringSet
+}
+
 type Types struct {
 	Int    int
 	Float  float64
@@ -90,6 +160,57 @@ var unmarshalTests = []testData{
 	{`"初次\u89c1\U00009762" = "你好，\u4e16\U0000754c！"`, new(Unicode), Unicode{"你好，世界！"}, nil},
 	{`t = 2016-01-07T15:30:30Z`, new(Datetime), Datetime{time.Date(2016, 1, 7, 15, 30, 30, 0, time.UTC)}, nil},
 	{`t = "2016-01-07T15:30:30Z"`, new(Datetime), Datetime{time.Date(2016, 1, 7, 15, 30, 30, 0, time.UTC)}, nil},
+	{`t = 2016-01-07T15:30:30.5+08:00`, new(Datetime), Datetime{time.Date(2016, 1, 7, 15, 30, 30, 5e8, time.FixedZone("", 8*3600))}, nil},
+	{
+		"date = 1979-05-27\ntime = 07:32:00.999\ndatetime = 1979-05-27 07:32:00\nt = 1979-05-27T07:32:00",
+		new(LocalDatetimes),
+		LocalDatetimes{
+			Date:     toml.LocalDate{1979, time.May, 27},
+			Time:     toml.LocalTime{7, 32, 0, 999000000},
+			DateTime: toml.LocalDateTime{toml.LocalDate{1979, time.May, 27}, toml.LocalTime{7, 32, 0, 0}},
+			T:        time.Date(1979, 5, 27, 7, 32, 0, 0, time.Local),
+		},
+		nil,
+	},
+	{
+		"date = '1979-05-27'\ntime = \"07:32:00\"\nt = 1979-05-27",
+		new(LocalDatetimes),
+		LocalDatetimes{
+			Date: toml.LocalDate{1979, time.May, 27},
+			Time: toml.LocalTime{7, 32, 0, 0},
+			T:    time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local),
+		},
+		nil,
+	},
+	{
+		"date = 1979-05-27\ntime = 00:32:00\ndatetime = 1979-05-27T00:32:00.5",
+		new(interface{}),
+		map[string]interface{}{
+			"date":     toml.LocalDate{1979, time.May, 27},
+			"time":     toml.LocalTime{0, 32, 0, 0},
+			"datetime": toml.LocalDateTime{toml.LocalDate{1979, time.May, 27}, toml.LocalTime{0, 32, 0, 500000000}},
+		},
+		nil,
+	},
+	{
+		"timeout = 30\ninterval = \"1m30s\"\ntags = [ \"a\", \"b\" ]",
+		new(Unmarshalers),
+		Unmarshalers{
+			Timeout:  Seconds(30 * time.Second),
+			Interval: func() *Seconds { s := Seconds(90 * time.Second); return &s }(),
+			Tags:     StringSet{"a": {}, "b": {}},
+		},
+		nil,
+	},
+	{
+		"a = [\n  1,\n  2 # two\n\n]\nb = [ 1e+06, 1E-7, -2e0_1 ]",
+		new(interface{}),
+		map[string]interface{}{
+			"a": []interface{}{int64(1), int64(2)},
+			"b": []interface{}{1e6, 1e-7, -2e1},
+		},
+		nil,
+	},
 	{`Key = "ignored"`, new(Ignore), Ignore{}, nil},
 	{`embed0 = 34_344_532`, new(IgnoreEmbed), IgnoreEmbed{}, nil},
 	{`integer = "123456"`, new(String), String{123456}, nil},
@@ -97,32 +218,32 @@ var unmarshalTests = []testData{
 	{
 		in:  `uint8 = 257`,
 		ptr: new(Overflow),
-		err: &toml.UnmarshalOverflowError{"integer 257", reflect.TypeOf(uint8(0))},
+		err: &toml.UnmarshalOverflowError{Value: "integer 257", Type: reflect.TypeOf(uint8(0)), Path: "uint8", Field: "Uint8", Line: 1, Column: 9},
 	},
 	{
 		in:  `uint8 = -1`,
 		ptr: new(Overflow),
-		err: &toml.UnmarshalOverflowError{"integer -1", reflect.TypeOf(uint8(0))},
+		err: &toml.UnmarshalOverflowError{Value: "integer -1", Type: reflect.TypeOf(uint8(0)), Path: "uint8", Field: "Uint8", Line: 1, Column: 9},
 	},
 	{
 		in:  `float32 = 3.4e+49`,
 		ptr: new(Overflow),
-		err: &toml.UnmarshalOverflowError{"float 3.4e+49", reflect.TypeOf(float32(0))},
+		err: &toml.UnmarshalOverflowError{Value: "float 3.4e+49", Type: reflect.TypeOf(float32(0)), Path: "float32", Field: "Float32", Line: 1, Column: 11},
 	},
 	{
 		in:  `int = "string"`,
 		ptr: new(Types),
-		err: &toml.UnmarshalTypeError{`string: "string"`, reflect.TypeOf(int(0))},
+		err: &toml.UnmarshalTypeError{Value: `string: "string"`, Type: reflect.TypeOf(int(0)), Path: "int", Field: "Int", Line: 1, Column: 7},
 	},
 	{
 		in:  `string = 233`,
 		ptr: new(Types),
-		err: &toml.UnmarshalTypeError{`integer 233`, reflect.TypeOf(string(""))},
+		err: &toml.UnmarshalTypeError{Value: `integer 233`, Type: reflect.TypeOf(string("")), Path: "string", Field: "String", Line: 1, Column: 10},
 	},
 	{
 		in:  `int = 233.5`,
 		ptr: new(Types),
-		err: &toml.UnmarshalTypeError{`float 233.5`, reflect.TypeOf(int(0))},
+		err: &toml.UnmarshalTypeError{Value: `float 233.5`, Type: reflect.TypeOf(int(0)), Path: "int", Field: "Int", Line: 1, Column: 7},
 	},
 	{
 		in: `
@@ -261,6 +382,150 @@ value'''
 			},
 		},
 	},
+	{
+		in: `
+		name.first = "Tom"
+		name . "last" = 'Preston-Werner'
+		site."google.com" = true
+		point = { x.value = 1, x.unit = "cm" }
+
+		[fruit]
+		apple.color = "red"
+
+		[fruit.apple.texture]
+		smooth = true
+		`,
+		ptr: new(interface{}),
+		out: map[string]interface{}{
+			"name": map[string]interface{}{"first": "Tom", "last": "Preston-Werner"},
+			"site": map[string]interface{}{"google.com": true},
+			"point": map[string]interface{}{
+				"x": map[string]interface{}{"value": int64(1), "unit": "cm"},
+			},
+			"fruit": map[string]interface{}{
+				"apple": map[string]interface{}{
+					"color":   "red",
+					"texture": map[string]interface{}{"smooth": true},
+				},
+			},
+		},
+	},
+	{
+		in:  "nested.ea = 'dotted'",
+		ptr: new(Struct),
+		out: Struct{Nested: Embeda{Ea: "dotted"}},
+	},
+	{
+		in:  "mask = 0xFF_ff\nmode = 0o7_55\nflags = 0b1010\nzero = 0x00",
+		ptr: new(interface{}),
+		out: map[string]interface{}{
+			"mask":  int64(0xffff),
+			"mode":  int64(0755),
+			"flags": int64(10),
+			"zero":  int64(0),
+		},
+	},
+	{
+		in:  "uint8 = 0x1FF",
+		ptr: new(Overflow),
+		err: &toml.UnmarshalOverflowError{Value: "integer 511", Type: reflect.TypeOf(uint8(0)), Path: "uint8", Field: "Uint8", Line: 1, Column: 9},
+	},
+	{
+		in:  "pos = inf\nneg = -inf\nplus = +inf\nfloats = [ -inf, +inf ]",
+		ptr: new(interface{}),
+		out: map[string]interface{}{
+			"pos":    math.Inf(1),
+			"neg":    math.Inf(-1),
+			"plus":   math.Inf(1),
+			"floats": []interface{}{math.Inf(-1), math.Inf(1)},
+		},
+	},
+	{
+		in:  "float32 = -inf",
+		ptr: new(Overflow),
+		out: Overflow{Float32: float32(math.Inf(-1))},
+	},
+	{
+		in:  `mixed = [ 1, "two", { x = 3 }, [ 4.0, false ] ]`,
+		ptr: new(interface{}),
+		out: map[string]interface{}{
+			"mixed": []interface{}{
+				int64(1),
+				"two",
+				map[string]interface{}{"x": int64(3)},
+				[]interface{}{float64(4), false},
+			},
+		},
+	},
+	{
+		in:  `timeout = true`,
+		ptr: new(Unmarshalers),
+		err: &toml.UnmarshalerError{Type: reflect.TypeOf(Seconds(0)), Err: errors.New("invalid duration: true"), Path: "timeout", Field: "Timeout", Line: 1, Column: 11},
+	},
+	{
+		in:  `string = 1979-05-27`,
+		ptr: new(Types),
+		err: &toml.UnmarshalTypeError{Value: "local date 1979-05-27", Type: reflect.TypeOf(""), Path: "string", Field: "String", Line: 1, Column: 10},
+	},
+	{
+		in:  "date = 1979-02-30",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 1, Column: 18, Pos: 17, Path: "date", Source: "date = 1979-02-30", Err: &time.ParseError{Layout: "2006-01-02", Value: "1979-02-30", Message: ": day out of range"}},
+	},
+	{
+		in:  "mode = -0o755",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 1, Column: 9, Pos: 8, Path: "mode", Source: "mode = -0o755", Err: errors.New("sign is not allowed in prefixed integer")},
+	},
+	{
+		in:  "f = 01.5",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 1, Column: 9, Pos: 8, Path: "f", Source: "f = 01.5", Err: errors.New(`leading zero in float "01.5"`)},
+	},
+	{
+		in:  "mode = 0o7__55",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 1, Column: 12, Pos: 11, Path: "mode", Source: "mode = 0o7__55", Err: errors.New("expect octal digit, got '_'")},
+	},
+	{
+		in:  "[fruit]\napple.color = 'red'\n[fruit.apple]",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 3, Column: 14, Pos: 41, Path: "fruit.apple", Source: "[fruit.apple]", Err: errors.New("table fruit.apple was defined twice")},
+	},
+	{
+		in:  "[a.b]\n[a]\nb.c = 1",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 3, Column: 6, Pos: 15, Path: "a.b", Source: "b.c = 1", Err: errors.New("table a.b was defined, can't be extended by dotted keys")},
+	},
+	{
+		in:  "a = { x = 1 }\na.y = 2",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 2, Column: 6, Pos: 19, Path: "a", Source: "a.y = 2", Err: errors.New("table a was defined, can't be extended by dotted keys")},
+	},
+	{
+		in:  "a = { b.c = 1 }\n[a.b.d]",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 2, Column: 8, Pos: 23, Path: "a.b.d", Source: "[a.b.d]", Err: errors.New("inline table a can't be extended by table header")},
+	},
+	{
+		in:  "a = { b = { c = 1 } }\n[[a.b.d]]",
+		ptr: new(interface{}),
+		err: &toml.ParseError{Line: 2, Column: 10, Pos: 31, Path: "a.b.d", Source: "[[a.b.d]]", Err: errors.New("inline table a can't be extended by table header")},
+	},
+}
+
+func TestUnmarshalNaN(t *testing.T) {
+	for _, in := range []string{"f = nan", "f = +nan", "f = -nan"} {
+		var out struct{ F float32 }
+		err := toml.Unmarshal([]byte(in), &out)
+		if err != nil {
+			t.Errorf("%q: got error: %s", in, err)
+			continue
+		}
+		if !math.IsNaN(float64(out.F)) {
+			t.Errorf("%q: got %v, want NaN", in, out.F)
+		}
+	}
 }
 
 func TestUnmarshal(t *testing.T) {
@@ -286,3 +551,600 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+var unmarshalVersionTests = []struct {
+	in      string
+	version toml.Version
+	out     interface{}
+	err     error
+}{
+	{"a.b = 1", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 6, Pos: 5, Source: "a.b = 1", Err: errors.New("dotted key is not supported in TOML v0.4.0")}},
+	{"a = 0x1", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 7, Pos: 6, Path: "a", Source: "a = 0x1", Err: errors.New("hexadecimal integer is not supported in TOML v0.4.0")}},
+	{"a = inf", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 8, Pos: 7, Path: "a", Source: "a = inf", Err: errors.New("float inf is not supported in TOML v0.4.0")}},
+	{"a = 1979-05-27", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 15, Pos: 14, Path: "a", Source: "a = 1979-05-27", Err: errors.New("local date is not supported in TOML v0.4.0")}},
+	{"a = 07:32:00", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 13, Pos: 12, Path: "a", Source: "a = 07:32:00", Err: errors.New("local time is not supported in TOML v0.4.0")}},
+	{"a = [1, 'a']", toml.V0_4_0, nil, &toml.ParseError{Line: 1, Column: 12, Pos: 11, Path: "a[1]", Source: "a = [1, 'a']", Err: errors.New("array a expects element type integer, but got string")}},
+	{"a = [1, 'a']", toml.V1_0_0, map[string]interface{}{"a": []interface{}{int64(1), "a"}}, nil},
+	{"a = { x = 1,\n y = 2 }", toml.V1_0_0, nil, &toml.ParseError{Line: 2, Column: 1, Pos: 13, Path: "a", Source: " y = 2 }", Err: errors.New("newline in inline table is not supported in TOML v1.0.0")}},
+	{"a = { x = 1, }", toml.V1_0_0, nil, &toml.ParseError{Line: 1, Column: 15, Pos: 14, Path: "a", Source: "a = { x = 1, }", Err: errors.New("trailing comma in inline table is not supported in TOML v1.0.0")}},
+	{`a = "\e"`, toml.V1_0_0, nil, &toml.ParseError{Line: 1, Column: 8, Pos: 7, Path: "a", Source: `a = "\e"`, Err: errors.New(`escape sequence \e is not supported in TOML v1.0.0`)}},
+	{"a = 07:32", toml.V1_0_0, nil, &toml.ParseError{Line: 1, Column: 10, Pos: 9, Path: "a", Source: "a = 07:32", Err: errors.New("expect ':', got EOF")}},
+	{
+		in: `a = {
+			x = 1, # comment
+			y = 2,
+		}
+		b = "\e\x41"
+		c = 07:32
+		d = 1979-05-27T07:32Z`,
+		version: toml.V1_1_0,
+		out: map[string]interface{}{
+			"a": map[string]interface{}{"x": int64(1), "y": int64(2)},
+			"b": "\x1bA",
+			"c": toml.LocalTime{7, 32, 0, 0},
+			"d": time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestUnmarshalVersion(t *testing.T) {
+	for i, test := range unmarshalVersionTests {
+		var out interface{}
+		err := toml.UnmarshalVersion([]byte(test.in), &out, test.version)
+
+		if test.err != nil {
+			if !reflect.DeepEqual(test.err, err) {
+				t.Errorf("#%d: error got %s\n, want %s", i, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(out, test.out) {
+			t.Errorf("#%d: got %+v\n, want %+v", i, out, test.out)
+		}
+	}
+}
+
+type errorDatabase struct{ Ports []int8 }
+type errorServer struct{ IP string }
+type errorConfig struct {
+	Database errorDatabase
+	Servers  map[string]errorServer
+	Products []struct{ Count uint8 }
+}
+
+var unmarshalErrorLocationTests = []struct {
+	in  string
+	err string
+}{
+	{
+		"[database]\nports = [ 80, 8080 ]",
+		"toml: line 2, column 15, key database.ports[1], field Database.Ports: integer 8080 overflow Go value of type int8",
+	},
+	{
+		"[servers.alpha]\nip = 10",
+		"toml: line 2, column 6, key servers.alpha.ip, field Servers.IP: cannot unmarshal integer 10 to Go value of type string",
+	},
+	{
+		"[[products]]\ncount = 1\n[[products]]\ncount = -1",
+		"toml: line 4, column 9, key products[1].count, field Products.Count: integer -1 overflow Go value of type uint8",
+	},
+	{
+		"[[database]]",
+		"toml: line 1, column 1, key database, field Database: cannot unmarshal array to Go value of type toml_test.errorDatabase",
+	},
+}
+
+func TestUnmarshalErrorLocation(t *testing.T) {
+	for _, test := range unmarshalErrorLocationTests {
+		err := toml.Unmarshal([]byte(test.in), new(errorConfig))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.in, err, test.err)
+		}
+	}
+	err := toml.Unmarshal([]byte("a = 1"), new(int))
+	if want := "toml: cannot unmarshal table to Go value of type int"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestParseErrorExcerpt(t *testing.T) {
+	err := toml.Unmarshal([]byte("[server]\n\tnom = 'é'; port = 80"), new(interface{}))
+	perr, ok := err.(*toml.ParseError)
+	if !ok {
+		t.Fatalf("got error %#v, want *toml.ParseError", err)
+	}
+	if want := "toml: line 2, column 11 (server): expect new line, comment or EOF, got ';'"; perr.Error() != want {
+		t.Errorf("got error %q, want %q", perr.Error(), want)
+	}
+	if want := "2 | \tnom = 'é'; port = 80\n  | \t         ^"; perr.Excerpt() != want {
+		t.Errorf("got excerpt:\n%s\nwant:\n%s", perr.Excerpt(), want)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestDecoder(t *testing.T) {
+	var out Struct
+	dec := toml.NewDecoder(strings.NewReader("sTrInG = 'ip4'\n[nested]\nea = \"ea\""))
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := Struct{STRING: "ip4", Nested: Embeda{Ea: "ea"}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	dec = toml.NewDecoder(strings.NewReader("a = 0x10"))
+	dec.SetVersion(toml.V0_4_0)
+	wantErr := &toml.ParseError{Line: 1, Column: 7, Pos: 6, Path: "a", Source: "a = 0x10", Err: errors.New("hexadecimal integer is not supported in TOML v0.4.0")}
+	if err := dec.Decode(new(interface{})); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+
+	readErr := errors.New("read error")
+	dec = toml.NewDecoder(errReader{readErr})
+	if err := dec.Decode(new(interface{})); err != readErr {
+		t.Errorf("got error %v, want %v", err, readErr)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	in := `
+	timout = "5s"
+	sTrInG = "string"
+
+	[nested]
+	ea = "ea"
+	eb = "eb"
+
+	[[tables]]
+	ea = "ea"
+	[[tables]]
+	x.y = 1
+
+	[extra]
+	`
+	var out struct {
+		Struct
+		Tables []Embeda
+	}
+	dec := toml.NewDecoder(strings.NewReader(in))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&out)
+	want := &toml.UnknownFieldError{Keys: []toml.UnknownKey{
+		{Path: "timout", Line: 2},
+		{Path: "nested.eb", Line: 7},
+		{Path: "tables[1].x", Line: 12},
+		{Path: "extra", Line: 14},
+	}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+
+	err = toml.Unmarshal([]byte(in), &out)
+	if err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+}
+
+func TestDecoderCollectErrors(t *testing.T) {
+	in := `
+	uint8 = 256
+	int8 = 1
+	float32 = 3.4e+49
+
+	[database]
+	ports = [ 80, 8080, "443" ]
+	`
+	type Database struct {
+		Ports []int8
+	}
+	var out struct {
+		Uint8    uint8
+		Int8     int8
+		Float32  float32
+		Database Database
+	}
+	dec := toml.NewDecoder(strings.NewReader(in))
+	dec.CollectErrors()
+	err := dec.Decode(&out)
+	want := &toml.UnmarshalErrors{Errors: []error{
+		&toml.UnmarshalOverflowError{Value: "integer 256", Type: reflect.TypeOf(uint8(0)), Path: "uint8", Field: "Uint8", Line: 2, Column: 10},
+		&toml.UnmarshalOverflowError{Value: "float 3.4e+49", Type: reflect.TypeOf(float32(0)), Path: "float32", Field: "Float32", Line: 4, Column: 12},
+		&toml.UnmarshalOverflowError{Value: "integer 8080", Type: reflect.TypeOf(int8(0)), Path: "database.ports[1]", Field: "Database.Ports", Line: 7, Column: 16},
+		&toml.UnmarshalTypeError{Value: `string: "443"`, Type: reflect.TypeOf(int8(0)), Path: "database.ports[2]", Field: "Database.Ports", Line: 7, Column: 22},
+	}}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+	if out.Int8 != 1 || !reflect.DeepEqual(out.Database.Ports, []int8{80, 0, 0}) {
+		t.Errorf("got %+v", out)
+	}
+	if msg := "toml: line 2, column 10, key uint8, field Uint8: integer 256 overflow Go value of type uint8 (and 3 more errors)"; err.Error() != msg {
+		t.Errorf("got error message %q, want %q", err.Error(), msg)
+	}
+	var typeErr *toml.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Path != "database.ports[2]" {
+		t.Errorf("errors.As: got %v", typeErr)
+	}
+	if !errors.Is(err, typeErr) {
+		t.Errorf("errors.Is: expect %v in %v", typeErr, err)
+	}
+
+	dec = toml.NewDecoder(strings.NewReader("timeout = true\ninterval = false\ntags = [ 'x' ]"))
+	dec.CollectErrors()
+	var unmarshalers Unmarshalers
+	err = dec.Decode(&unmarshalers)
+	want = &toml.UnmarshalErrors{Errors: []error{
+		&toml.UnmarshalerError{Type: reflect.TypeOf(Seconds(0)), Err: errors.New("invalid duration: true"), Path: "timeout", Field: "Timeout", Line: 1, Column: 11},
+		&toml.UnmarshalerError{Type: reflect.TypeOf(Seconds(0)), Err: errors.New("invalid duration: false"), Path: "interval", Field: "Interval", Line: 2, Column: 12},
+	}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+	if _, ok := unmarshalers.Tags["x"]; !ok {
+		t.Errorf("got tags %v, want x", unmarshalers.Tags)
+	}
+}
+
+type defaultServer struct {
+	Host string `default:"'localhost'"`
+	Port int    `toml:"port" default:"8080"`
+}
+
+type defaultConfig struct {
+	Name     string   `default:"\"app\""`
+	Tags     []string `default:"[ 'a', 'b' ]"`
+	Timeout  float64  `toml:",omitempty" default:"1.5"`
+	Server   defaultServer
+	Backends []defaultServer `default:"[ { host = 'backend' } ]"`
+	Replicas []defaultServer
+}
+
+func TestUnmarshalDefault(t *testing.T) {
+	in := `
+	name = "svc"
+
+	[[replicas]]
+	host = "r1"
+
+	[[replicas]]
+	port = 9090
+	`
+	out := defaultConfig{Timeout: 3}
+	md, err := toml.UnmarshalMetaData([]byte(in), &out)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := defaultConfig{
+		Name:     "svc",
+		Tags:     []string{"a", "b"},
+		Timeout:  1.5,
+		Server:   defaultServer{Host: "localhost", Port: 8080},
+		Backends: []defaultServer{{Host: "backend", Port: 8080}},
+		Replicas: []defaultServer{{Host: "r1", Port: 8080}, {Host: "localhost", Port: 9090}},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+	if md.IsDecoded("tags") || md.IsDecoded("server.port") {
+		t.Errorf("default values reported as decoded")
+	}
+
+	var bad struct {
+		Port int `default:"'8080'"`
+	}
+	err = toml.Unmarshal(nil, &bad)
+	var typeErr *toml.UnmarshalTypeError
+	if _, ok := err.(*toml.DefaultValueError); !ok || !errors.As(err, &typeErr) {
+		t.Errorf("got error %#v, want *toml.DefaultValueError of *toml.UnmarshalTypeError", err)
+	}
+	if want := `toml: key port, field Port: invalid default value "'8080'" for Go value of type int: cannot unmarshal string: "8080" to Go value of type int`; err == nil || err.Error() != want {
+		t.Errorf("got error message %v, want %s", err, want)
+	}
+	dec := toml.NewDecoder(strings.NewReader(""))
+	dec.CollectErrors()
+	err = dec.Decode(&bad)
+	if errs, ok := err.(*toml.UnmarshalErrors); !ok || len(errs.Errors) != 1 || !errors.As(err, &typeErr) {
+		t.Errorf("got error %#v, want *toml.UnmarshalErrors of *toml.DefaultValueError", err)
+	}
+
+	type invalidServer struct {
+		Port int `default:"80 80"`
+		Host string
+	}
+	var invalid struct {
+		Server invalidServer
+	}
+	err = toml.Unmarshal(nil, &invalid)
+	var defaultErr *toml.DefaultValueError
+	if !errors.As(err, &defaultErr) || defaultErr.Path != "server.port" || defaultErr.Field != "Server.Port" || defaultErr.Type != reflect.TypeOf(0) {
+		t.Errorf("got error %#v, want *toml.DefaultValueError", err)
+	}
+	if want := `toml: key server.port, field Server.Port: invalid default value "80 80" for Go value of type int: expect new line, comment or EOF, got '8'`; err == nil || err.Error() != want {
+		t.Errorf("got error message %v, want %s", err, want)
+	}
+
+	dec = toml.NewDecoder(strings.NewReader("[server]\nhost = 1\n"))
+	dec.CollectErrors()
+	err = dec.Decode(&invalid)
+	errs, ok := err.(*toml.UnmarshalErrors)
+	if !ok || len(errs.Errors) != 2 {
+		t.Fatalf("got error %#v, want *toml.UnmarshalErrors of 2 errors", err)
+	}
+	if _, ok := errs.Errors[0].(*toml.DefaultValueError); !ok {
+		t.Errorf("got error %#v, want *toml.DefaultValueError", errs.Errors[0])
+	}
+	if typeErr, ok := errs.Errors[1].(*toml.UnmarshalTypeError); !ok || typeErr.Path != "server.host" {
+		t.Errorf("got error %#v, want *toml.UnmarshalTypeError of server.host", errs.Errors[1])
+	}
+}
+
+type requiredServer struct {
+	Host string `toml:",required"`
+	Port int    `toml:"port,required"`
+}
+
+type requiredConfig struct {
+	Name     string `toml:"name,required"`
+	Server   requiredServer
+	Replicas []requiredServer
+	Backup   *requiredServer
+}
+
+func TestUnmarshalRequired(t *testing.T) {
+	in := `
+	[server]
+	host = "localhost"
+
+	[[replicas]]
+	host = "r1"
+	port = 8080
+
+	[[replicas]]
+	`
+	err := toml.Unmarshal([]byte(in), new(requiredConfig))
+	want := &toml.RequiredFieldError{Keys: []string{"name", "replicas[1].host", "replicas[1].port", "server.port"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+	if msg := "toml: missing required keys: name, replicas[1].host, replicas[1].port, server.port"; err == nil || err.Error() != msg {
+		t.Errorf("got error message %v, want %s", err, msg)
+	}
+
+	err = toml.Unmarshal([]byte("name = 'app'"), new(requiredConfig))
+	want = &toml.RequiredFieldError{Keys: []string{"server.host", "server.port"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+
+	in = "name = 'app'\n[server]\nhost = 'h'\nport = 1"
+	if err := toml.Unmarshal([]byte(in), new(requiredConfig)); err != nil {
+		t.Errorf("got error %v", err)
+	}
+}
+
+type Durations struct {
+	Timeout  time.Duration
+	Interval time.Duration
+	Retries  []time.Duration
+}
+
+func TestUnmarshalDuration(t *testing.T) {
+	in := `
+	timeout = "1m30s"
+	interval = 5
+	retries = [ "1s", "-2.5ms" ]
+	`
+	var out Durations
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := Durations{Timeout: 90 * time.Second, Interval: 5, Retries: []time.Duration{time.Second, -2500 * time.Microsecond}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	dec := toml.NewDecoder(strings.NewReader(in))
+	dec.SetDurationUnit(time.Second)
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if out.Interval != 5*time.Second {
+		t.Errorf("got interval %s, want 5s", out.Interval)
+	}
+
+	dec = toml.NewDecoder(strings.NewReader("interval = 9223372037"))
+	dec.SetDurationUnit(time.Second)
+	err := dec.Decode(&out)
+	if _, ok := err.(*toml.UnmarshalOverflowError); !ok {
+		t.Errorf("got error %#v, want *toml.UnmarshalOverflowError", err)
+	}
+
+	err = toml.Unmarshal([]byte(`timeout = "90"`), &out)
+	wantErr := &toml.UnmarshalTypeError{Value: `string: "90"`, Type: reflect.TypeOf(time.Duration(0)), Path: "timeout", Field: "Timeout", Line: 1, Column: 11}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+}
+
+type BigNumbers struct {
+	Max    uint64
+	Int    *big.Int
+	Float  *big.Float
+	Number toml.Number
+	Any    interface{}
+}
+
+func TestUnmarshalBigNumbers(t *testing.T) {
+	in := `
+	max = 18446744073709551615
+	int = 123456789012345678901234567890
+	float = 1.5
+	number = -123456789012345678901234567890
+	any = 99999999999999999999
+	`
+	var out BigNumbers
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	anyInt, _ := new(big.Int).SetString("99999999999999999999", 10)
+	want := BigNumbers{
+		Max:    math.MaxUint64,
+		Int:    bigInt,
+		Float:  big.NewFloat(1.5),
+		Number: "-123456789012345678901234567890",
+		Any:    anyInt,
+	}
+	if out.Max != want.Max || out.Int.Cmp(want.Int) != 0 || out.Float.Cmp(want.Float) != 0 || out.Number != want.Number || out.Any.(*big.Int).Cmp(anyInt) != 0 {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+
+	dec := toml.NewDecoder(strings.NewReader("any = [ 99999999999999999999, 0x10, 1_000, 1e6, -inf ]"))
+	dec.UseNumber()
+	out = BigNumbers{}
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	wantAny := []interface{}{toml.Number("99999999999999999999"), toml.Number("0x10"), toml.Number("1_000"), toml.Number("1e6"), toml.Number("-inf")}
+	if !reflect.DeepEqual(out.Any, wantAny) {
+		t.Errorf("got %#v, want %#v", out.Any, wantAny)
+	}
+
+	// Literal text keeps digits beyond precision of float64.
+	const pi = "3.14159265358979323846264338327950288"
+	in = "max = 0xFFFF_FFFF_FFFF_FFFF\nint = 0x1_0000_0000_0000_0000\nfloat = " + pi + "\nnumber = " + pi
+	out = BigNumbers{}
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if out.Max != math.MaxUint64 || out.Int.String() != "18446744073709551616" || out.Number != pi {
+		t.Errorf("got %+v", out)
+	}
+	if got := out.Float.Text('g', -1); got != pi {
+		t.Errorf("got float %s, want %s", got, pi)
+	}
+	out.Float = new(big.Float).SetPrec(24)
+	if err := toml.Unmarshal([]byte("float = "+pi), &out); err != nil || out.Float.Prec() != 24 {
+		t.Errorf("got float %v of precision %d, error %v, want precision 24", out.Float, out.Float.Prec(), err)
+	}
+
+	err := toml.Unmarshal([]byte("int = 1.5"), &out)
+	if _, ok := err.(*toml.UnmarshalTypeError); !ok {
+		t.Errorf("got error %#v, want *toml.UnmarshalTypeError", err)
+	}
+
+	var overflow struct{ Max int64 }
+	err = toml.Unmarshal([]byte("max = 18446744073709551615"), &overflow)
+	wantErr := &toml.UnmarshalOverflowError{Value: "integer 18446744073709551615", Type: reflect.TypeOf(int64(0)), Path: "max", Field: "Max", Line: 1, Column: 7}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+
+	root, err := toml.Parse([]byte("max = 18446744073709551616"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := root.Get("max"); got != toml.Number("18446744073709551616") || got.Type() != "integer" {
+		t.Errorf("got %#v, want toml.Number(\"18446744073709551616\")", got)
+	}
+
+	// Floats out of range of float64 fail only in float32 and float64.
+	in = "float = -1e400\nnumber = 1_000e400\nany = 1e+400"
+	out = BigNumbers{}
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := out.Float.Text('g', -1); got != "-1e+400" || out.Number != "1_000e400" {
+		t.Errorf("got %+v", out)
+	}
+	if f, ok := out.Any.(*big.Float); !ok || f.Text('g', -1) != "1e+400" {
+		t.Errorf("got any %#v, want *big.Float 1e+400", out.Any)
+	}
+	var float struct{ Float float64 }
+	err = toml.Unmarshal([]byte("float = -1e400"), &float)
+	wantErr = &toml.UnmarshalOverflowError{Value: "float -1e400", Type: reflect.TypeOf(float64(0)), Path: "float", Field: "Float", Line: 1, Column: 9}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+	root, err = toml.Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := root.Get("any"); got != toml.Number("1e+400") || got.Type() != "float" {
+		t.Errorf("got %#v, want toml.Number(\"1e+400\")", got)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for _, test := range []struct {
+		n toml.Number
+		i int64
+	}{
+		{"-42", -42},
+		{"+1_000", 1000},
+		{"0xe", 14},
+		{"0x1E", 30},
+		{"0xdead_BEEF", 0xdeadbeef},
+		{"0o755", 0755},
+		{"0b1010", 10},
+		{"010", 10},
+		{"-0_10", -10},
+	} {
+		if typ := test.n.Type(); typ != "integer" {
+			t.Errorf("%s: got type %s, want integer", test.n, typ)
+		}
+		if i, err := test.n.Int64(); err != nil || i != test.i {
+			t.Errorf("%s: Int64: got %d, %v, want %d", test.n, i, err, test.i)
+		}
+		if f, err := test.n.Float64(); err != nil || f != float64(test.i) {
+			t.Errorf("%s: Float64: got %g, %v, want %d", test.n, f, err, test.i)
+		}
+	}
+	for _, n := range []toml.Number{"1.5", "1e6", "1_000.5e-1_0", "inf", "-inf", "nan"} {
+		if typ := n.Type(); typ != "float" {
+			t.Errorf("%s: got type %s, want float", n, typ)
+		}
+		if _, err := n.Float64(); err != nil {
+			t.Errorf("%s: Float64: got error %v", n, err)
+		}
+	}
+	for _, n := range []toml.Number{"0x-1", "0x+1", "1.5", "0x1_0000_0000_0000_0000"} {
+		if i, err := n.Int64(); err == nil {
+			t.Errorf("%s: Int64: got %d, want error", n, i)
+		}
+	}
+	if f, err := toml.Number("0x1_0000_0000_0000_0000").Float64(); err != nil || f != 1<<64 {
+		t.Errorf("Float64: got %g, %v, want 2^64", f, err)
+	}
+}
+
+func TestUnmarshalGoArray(t *testing.T) {
+	var out struct {
+		Ports  [3]int
+		Matrix [2][2]int
+		Ptr    *[2]string
+	}
+	in := "ports = [ 80, 443, 8080 ]\nmatrix = [ [ 1, 2 ], [ 3, 4 ] ]\nptr = [ 'a', 'b' ]"
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if out.Ports != [3]int{80, 443, 8080} || out.Matrix != [2][2]int{{1, 2}, {3, 4}} || out.Ptr == nil || *out.Ptr != [2]string{"a", "b"} {
+		t.Errorf("got %+v", out)
+	}
+
+	err := toml.Unmarshal([]byte("ports = [ 80, 443 ]"), &out)
+	want := &toml.UnmarshalTypeError{Value: "[2]array", Type: reflect.TypeOf([3]int{}), Path: "ports", Field: "Ports", Line: 1, Column: 9}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,616 @@
+package toml
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kezhuw/toml/internal/types"
+)
+
+// EditError describes a failed edit of Document.
+type EditError struct {
+	Path string
+	Msg  string
+}
+
+func (e *EditError) Error() string {
+	return "toml: edit " + e.Path + ": " + e.Msg
+}
+
+// Document is a TOML document which could be edited with its comments,
+// whitespace and formatting preserved. Parts of document not touched by
+// edits are kept byte-identical.
+//
+// Key/value pairs are edited in place. New key/value pairs are inserted
+// after the last key/value pair of the table they belong to. New tables
+// and arrays of tables are inserted as sections after the section of
+// their parent table, or at the end of document for top level ones.
+// Edits inside inline tables and arrays rewrite the outermost inline
+// value enclosing them.
+type Document struct {
+	version Version
+	src     []byte
+	root    *types.Table
+}
+
+// ParseDocument parses TOML data in DefaultVersion of TOML specification
+// as Document. Use Decoder.ParseDocument to parse other versions.
+func ParseDocument(data []byte) (*Document, error) {
+	return parseDocument(data, DefaultVersion)
+}
+
+// parseDocument parses data in given version of TOML specification as
+// Document. Edits of the document are encoded in the same version.
+func parseDocument(data []byte, version Version) (*Document, error) {
+	src := append([]byte(nil), data...)
+	root, err := parse(src, version)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{version: version, src: src, root: root}, nil
+}
+
+// Bytes returns content of d.
+func (d *Document) Bytes() []byte {
+	return append([]byte(nil), d.src...)
+}
+
+// String returns content of d.
+func (d *Document) String() string {
+	return string(d.src)
+}
+
+// Table returns document tree of d. Changes to the returned table are not
+// reflected in d.
+func (d *Document) Table() *Table {
+	return treeTable(d.root)
+}
+
+// location describes where the last key or index of a key path resides.
+type location struct {
+	path string
+
+	parent *types.Table // table containing the last key
+	key    string
+
+	array *types.Array // array of tables containing the last index
+	index int
+
+	section *types.Table // table whose section defines parent, nil if parent is implicit
+	prefix  []string     // keys from section to parent
+	headers []string     // keys from root to parent, as in table headers
+
+	owner    *types.Table // table containing key/value pair enclosing path
+	ownerKey string
+	steps    []pathStep // steps after owner key
+}
+
+func (d *Document) locate(path string) (*location, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if step.wildcard {
+			return nil, &PathError{Path: path, Offset: step.offset, Msg: "wildcard is not allowed"}
+		}
+	}
+	loc := &location{path: path, section: d.root}
+	var cur types.Value = d.root
+	var curPath string
+	for i, step := range steps {
+		last := i == len(steps)-1
+		switch c := cur.(type) {
+		case *types.Table:
+			if step.index >= 0 {
+				return nil, &EditError{Path: path, Msg: curPath + " is not an array"}
+			}
+			if last {
+				loc.parent, loc.key = c, step.key
+				return loc, nil
+			}
+			if _, ok := c.Fields[step.key]; ok {
+				loc.owner, loc.ownerKey, loc.steps = c, step.key, steps[i+1:]
+				return loc, nil
+			}
+			curPath = combineKeyPath(curPath, step.key)
+			next, ok := c.Elems[step.key].(*types.Table)
+			if !ok {
+				if c.Elems[step.key] == nil {
+					return nil, &EditError{Path: path, Msg: curPath + " is not defined"}
+				}
+				cur = c.Elems[step.key]
+				loc.headers = append(loc.headers, step.key)
+				continue
+			}
+			loc.headers = append(loc.headers, step.key)
+			switch {
+			case next.Header.End != 0:
+				loc.section, loc.prefix = next, nil
+			case next.Dotted:
+				loc.prefix = append(loc.prefix, step.key)
+			default:
+				loc.section, loc.prefix = nil, nil
+			}
+			cur = next
+		case *types.Array:
+			if step.index < 0 {
+				return nil, &EditError{Path: path, Msg: curPath + " is not a table"}
+			}
+			if step.index >= len(c.Elems) {
+				return nil, &EditError{Path: path, Msg: combineIndexPath(curPath, step.index) + " is not defined"}
+			}
+			if last {
+				loc.array, loc.index = c, step.index
+				return loc, nil
+			}
+			curPath = combineIndexPath(curPath, step.index)
+			next := c.Elems[step.index].(*types.Table)
+			loc.section, loc.prefix = next, nil
+			cur = next
+		default:
+			return nil, &EditError{Path: path, Msg: curPath + " is not a table"}
+		}
+	}
+	return loc, nil
+}
+
+// Set sets value at key path to v. Key path is in form of what Query
+// accepts. Tables containing the key must exist.
+//
+// Tables and arrays of tables defined by table headers are replaced by
+// new sections. Other values are written in place, tables and arrays of
+// tables are written as inline values in this case.
+func (d *Document) Set(path string, v Value) error {
+	if v == nil {
+		return &EditError{Path: path, Msg: "nil value"}
+	}
+	loc, err := d.locate(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case loc.owner != nil:
+		return d.editInline(loc, func(parent Value, step pathStep) string {
+			switch parent := parent.(type) {
+			case *Table:
+				if step.index < 0 {
+					parent.Set(step.key, v)
+					return ""
+				}
+			case *Array:
+				if step.index >= 0 {
+					if step.index >= parent.Len() {
+						return "index out of range"
+					}
+					parent.Set(step.index, v)
+					return ""
+				}
+			}
+			return parentError(step)
+		})
+	case loc.array != nil:
+		return &EditError{Path: path, Msg: "can't set element of array of tables"}
+	}
+	t, key := loc.parent, loc.key
+	if f, ok := t.Fields[key]; ok {
+		value, err := d.formatValue(v)
+		if err != nil {
+			return err
+		}
+		return d.apply(path, []edit{{f.Value.Start, f.Value.End, value}})
+	}
+	if t.Elems[key] != nil {
+		if err := d.Delete(path); err != nil {
+			return err
+		}
+		return d.Set(path, v)
+	}
+	if isTreeTable(v) {
+		return d.insertSections(loc, v)
+	}
+	return d.insertField(loc, v)
+}
+
+// Delete deletes value at key path. Key path is in form of what Query
+// accepts. Deleting a table also deletes all its sub-tables.
+func (d *Document) Delete(path string) error {
+	loc, err := d.locate(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case loc.owner != nil:
+		return d.editInline(loc, func(parent Value, step pathStep) string {
+			switch parent := parent.(type) {
+			case *Table:
+				if step.index < 0 {
+					if parent.Get(step.key) == nil {
+						return "key is not defined"
+					}
+					parent.Delete(step.key)
+					return ""
+				}
+			case *Array:
+				if step.index >= 0 {
+					if step.index >= parent.Len() {
+						return "index out of range"
+					}
+					parent.Remove(step.index)
+					return ""
+				}
+			}
+			return parentError(step)
+		})
+	case loc.array != nil:
+		ranges := d.tableRanges(loc.array.Elems[loc.index].(*types.Table), nil)
+		return d.apply(path, d.deletions(ranges))
+	}
+	t, key := loc.parent, loc.key
+	if f, ok := t.Fields[key]; ok {
+		return d.apply(path, d.deletions([]types.Range{d.fieldRange(f)}))
+	}
+	var ranges []types.Range
+	switch v := t.Elems[key].(type) {
+	case nil:
+		return &EditError{Path: path, Msg: "key is not defined"}
+	case *types.Table:
+		ranges = d.tableRanges(v, ranges)
+	case *types.Array:
+		for _, elem := range v.Elems {
+			ranges = d.tableRanges(elem.(*types.Table), ranges)
+		}
+	}
+	return d.apply(path, d.deletions(ranges))
+}
+
+func parentError(step pathStep) string {
+	if step.index >= 0 {
+		return "parent is not an array"
+	}
+	return "parent is not a table"
+}
+
+// editInline applies fn to value enclosing the last step in inline value
+// of loc.owner, and rewrites that inline value.
+func (d *Document) editInline(loc *location, fn func(parent Value, step pathStep) string) error {
+	f := loc.owner.Fields[loc.ownerKey]
+	root := treeValue(loc.owner.Elems[loc.ownerKey])
+	parent, steps := root, loc.steps
+	for len(steps) > 1 {
+		step := steps[0]
+		steps = steps[1:]
+		var next Value
+		switch p := parent.(type) {
+		case *Table:
+			if step.index < 0 {
+				next = p.Get(step.key)
+			}
+		case *Array:
+			if step.index >= 0 && step.index < p.Len() {
+				next = p.Index(step.index)
+			}
+		}
+		if next == nil {
+			return &EditError{Path: loc.path, Msg: "parent is not defined"}
+		}
+		parent = next
+	}
+	if msg := fn(parent, steps[0]); msg != "" {
+		return &EditError{Path: loc.path, Msg: msg}
+	}
+	value, err := d.formatValue(root)
+	if err != nil {
+		return err
+	}
+	return d.apply(loc.path, []edit{{f.Value.Start, f.Value.End, value}})
+}
+
+// insertField inserts key/value pair after the last key/value pair in
+// section of parent table.
+func (d *Document) insertField(loc *location, v Value) error {
+	value, err := d.formatValue(v)
+	if err != nil {
+		return err
+	}
+	field := joinKeys(append(loc.prefix[:len(loc.prefix):len(loc.prefix)], loc.key)) + " = " + value
+	if loc.section == nil {
+		return d.appendSection(loc.path, "["+joinKeys(loc.headers)+"]\n"+field)
+	}
+	end, indent := d.fieldsEnd(loc.section)
+	if end < 0 {
+		return d.apply(loc.path, []edit{{0, 0, field + "\n"}})
+	}
+	return d.apply(loc.path, []edit{{end, end, "\n" + indent + field}})
+}
+
+// insertSections inserts table or array of tables v as sections after
+// section of parent table.
+func (d *Document) insertSections(loc *location, v Value) (err error) {
+	defer catchError(&err)
+	e := &encodeState{version: d.version}
+	sup := &table{Path: joinKeys(loc.headers)}
+	e.marshalTables(sup, []field{{key: loc.key, value: reflect.ValueOf(treeInterface(v))}})
+	if loc.section == nil || loc.section == d.root {
+		return d.appendSection(loc.path, e.String())
+	}
+	end := d.sectionEnd(loc.section)
+	if end == len(d.src) {
+		return d.appendSection(loc.path, e.String())
+	}
+	return d.apply(loc.path, []edit{{end, end, e.String() + "\n\n"}})
+}
+
+// appendSection appends section to the end of document, separated by an
+// empty line.
+func (d *Document) appendSection(path string, section string) error {
+	var prefix string
+	if n := len(d.src); n != 0 {
+		if d.src[n-1] != '\n' {
+			prefix = "\n"
+		}
+		prefix += "\n"
+	}
+	end := len(d.src)
+	return d.apply(path, []edit{{end, end, prefix + section + "\n"}})
+}
+
+func (d *Document) formatValue(v Value) (s string, err error) {
+	defer catchError(&err)
+	e := &encodeState{version: d.version}
+	e.marshalTableField(&table{Inline: true}, "", reflect.ValueOf(treeInterface(v)), tagOptions{"inline": {}})
+	return strings.TrimPrefix(e.String(), " = "), nil
+}
+
+// isTreeTable reports whether v is a table or non-empty array of tables.
+func isTreeTable(v Value) bool {
+	switch v := v.(type) {
+	case *Table:
+		return true
+	case *Array:
+		for _, elem := range v.elems {
+			if _, ok := elem.(*Table); !ok {
+				return false
+			}
+		}
+		return v.Len() != 0
+	}
+	return false
+}
+
+func joinKeys(keys []string) string {
+	var path string
+	for _, key := range keys {
+		path = combineKeyPath(path, key)
+	}
+	return path
+}
+
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+// apply applies non-overlapping edits to d.
+func (d *Document) apply(path string, edits []edit) error {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		buf.Write(d.src[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+	buf.Write(d.src[last:])
+	src := buf.Bytes()
+	root, err := parse(src, d.version)
+	if err != nil {
+		return &EditError{Path: path, Msg: "result is invalid: " + err.Error()}
+	}
+	d.src, d.root = src, root
+	return nil
+}
+
+// deletions merges overlapping ranges into deletion edits.
+func (d *Document) deletions(ranges []types.Range) []edit {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	var edits []edit
+	for _, r := range ranges {
+		if n := len(edits); n != 0 && r.Start <= edits[n-1].end {
+			if r.End > edits[n-1].end {
+				edits[n-1].end = r.End
+			}
+			continue
+		}
+		edits = append(edits, edit{r.Start, r.End, ""})
+	}
+	return edits
+}
+
+func (d *Document) lineStart(off int) int {
+	return bytes.LastIndexByte(d.src[:off], '\n') + 1
+}
+
+func (d *Document) lineEnd(off int) int {
+	if i := bytes.IndexByte(d.src[off:], '\n'); i >= 0 {
+		end := off + i
+		if end > 0 && d.src[end-1] == '\r' {
+			end--
+		}
+		return end
+	}
+	return len(d.src)
+}
+
+// lineIndent returns start of line containing off if there are only
+// whitespace before off in that line, otherwise off.
+func (d *Document) lineIndent(off int) int {
+	start := d.lineStart(off)
+	if len(bytes.TrimLeft(d.src[start:off], " \t")) != 0 {
+		return off
+	}
+	return start
+}
+
+// linesAbove returns start of consecutive lines satisfying pred right
+// above line starting at off.
+func (d *Document) linesAbove(off int, pred func(line []byte) bool) int {
+	for off > 0 {
+		start := d.lineStart(off - 1)
+		if !pred(bytes.TrimSpace(d.src[start:off])) {
+			break
+		}
+		off = start
+	}
+	return off
+}
+
+func isCommentLine(line []byte) bool {
+	return len(line) != 0 && line[0] == '#'
+}
+
+func isBlankLine(line []byte) bool {
+	return len(line) == 0
+}
+
+// commentsAbove returns start of comment lines right above line starting
+// at off.
+func (d *Document) commentsAbove(off int) int {
+	return d.linesAbove(off, isCommentLine)
+}
+
+// fieldRange returns range of key/value pair f, including its leading
+// indentation, trailing comment and newline.
+func (d *Document) fieldRange(f types.Field) types.Range {
+	start, end := d.lineIndent(f.Key.Start), f.Value.End
+	for end < len(d.src) && (d.src[end] == ' ' || d.src[end] == '\t') {
+		end++
+	}
+	if end < len(d.src) && d.src[end] == '#' {
+		end = d.lineEnd(end)
+	}
+	if end < len(d.src) && d.src[end] == '\r' {
+		end++
+	}
+	if end < len(d.src) && d.src[end] == '\n' {
+		end++
+	}
+	return types.Range{Start: start, End: end}
+}
+
+// headers returns starts of all table headers in order.
+func (d *Document) headers() []int {
+	var starts []int
+	var walk func(v types.Value)
+	walk = func(v types.Value) {
+		switch v := v.(type) {
+		case *types.Table:
+			if v.Inline {
+				return
+			}
+			if v.Header.End != 0 {
+				starts = append(starts, v.Header.Start)
+			}
+			for _, elem := range v.Elems {
+				walk(elem)
+			}
+		case *types.Array:
+			for _, elem := range v.Elems {
+				walk(elem)
+			}
+		}
+	}
+	walk(d.root)
+	sort.Ints(starts)
+	return starts
+}
+
+// sectionEnd returns end of section of table t, excluding comments right
+// above the next table header.
+func (d *Document) sectionEnd(t *types.Table) int {
+	start := -1
+	if t != d.root {
+		start = t.Header.Start
+	}
+	for _, header := range d.headers() {
+		if header > start {
+			return d.commentsAbove(d.lineIndent(header))
+		}
+	}
+	return len(d.src)
+}
+
+// fieldsEnd returns end of line of the last key/value pair in section of
+// table t and indentation of that line. If there is no key/value pair in
+// that section, it returns end of line of table header, or -1 for root
+// table.
+func (d *Document) fieldsEnd(t *types.Table) (int, string) {
+	start, end := 0, d.sectionEnd(t)
+	if t != d.root {
+		start = t.Header.Start
+	}
+	last := types.Field{Value: types.Range{Start: -1, End: -1}}
+	var walk func(v types.Value)
+	walk = func(v types.Value) {
+		switch v := v.(type) {
+		case *types.Table:
+			if v.Inline {
+				return
+			}
+			for _, f := range v.Fields {
+				if f.Key.Start >= start && f.Key.Start < end && f.Value.End > last.Value.End {
+					last = f
+				}
+			}
+			for _, elem := range v.Elems {
+				walk(elem)
+			}
+		case *types.Array:
+			for _, elem := range v.Elems {
+				walk(elem)
+			}
+		}
+	}
+	walk(d.root)
+	switch {
+	case last.Value.End >= 0:
+		var indent string
+		if start := d.lineIndent(last.Key.Start); start != last.Key.Start {
+			indent = string(d.src[start:last.Key.Start])
+		}
+		return d.lineEnd(last.Value.End), indent
+	case t != d.root:
+		return d.lineEnd(t.Header.End), ""
+	}
+	return -1, ""
+}
+
+// tableRanges appends ranges of sections and key/value pairs defining t
+// and its sub-tables to ranges.
+func (d *Document) tableRanges(t *types.Table, ranges []types.Range) []types.Range {
+	if t.Header.End != 0 {
+		start, end := d.commentsAbove(d.lineIndent(t.Header.Start)), d.sectionEnd(t)
+		if end == len(d.src) {
+			start = d.linesAbove(start, isBlankLine)
+		}
+		ranges = append(ranges, types.Range{Start: start, End: end})
+	}
+	for _, key := range t.Keys {
+		if f, ok := t.Fields[key]; ok {
+			ranges = append(ranges, d.fieldRange(f))
+			continue
+		}
+		switch v := t.Elems[key].(type) {
+		case *types.Table:
+			ranges = d.tableRanges(v, ranges)
+		case *types.Array:
+			for _, elem := range v.Elems {
+				ranges = d.tableRanges(elem.(*types.Table), ranges)
+			}
+		}
+	}
+	return ranges
+}
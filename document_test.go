@@ -0,0 +1,308 @@
+package toml_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kezhuw/toml"
+)
+
+type documentEdit struct {
+	path  string
+	value toml.Value // nil for deletion
+}
+
+func newServerTable() *toml.Table {
+	t := toml.NewTable()
+	t.Set("ip", toml.String("10.0.0.3"))
+	return t
+}
+
+var documentTests = []struct {
+	in    string
+	edits []documentEdit
+	out   string
+}{
+	{
+		in: `# version of package
+version = "1.0.0"  # bumped by release script
+name    = 'pkg'
+`,
+		edits: []documentEdit{{"version", toml.String("1.0.1")}},
+		out: `# version of package
+version = "1.0.1"  # bumped by release script
+name    = 'pkg'
+`,
+	},
+	{
+		in: `name = "pkg"
+
+[deps]
+  a = "1.0"   # pinned
+  b = "2.0"
+
+[dev]
+c = "3.0"
+`,
+		edits: []documentEdit{{"deps.z", toml.String("0.1")}, {"top", toml.Boolean(true)}},
+		out: `name = "pkg"
+top = true
+
+[deps]
+  a = "1.0"   # pinned
+  b = "2.0"
+  z = "0.1"
+
+[dev]
+c = "3.0"
+`,
+	},
+	{
+		in: `[deps]
+a = "1.0"   # pinned
+b = "2.0"
+`,
+		edits: []documentEdit{{"deps.a", nil}},
+		out: `[deps]
+b = "2.0"
+`,
+	},
+	{
+		in:    `point = { x = 1, y = 2 }`,
+		edits: []documentEdit{{"point.y", nil}, {"point.z", toml.Integer(3)}},
+		out:   `point = { x = 1, z = 3}`,
+	},
+	{
+		in:    `ports = [ 8080, 8081 ] # ports`,
+		edits: []documentEdit{{"ports[1]", toml.Integer(9090)}},
+		out:   `ports = [ 8080, 9090 ] # ports`,
+	},
+	{
+		in: `a.b = 1
+a.c = 2
+
+[x]
+y.z = 1
+`,
+		edits: []documentEdit{{"a.d", toml.Integer(3)}, {"x.y.w", toml.Integer(2)}, {"a.b", nil}},
+		out: `a.c = 2
+a.d = 3
+
+[x]
+y.z = 1
+y.w = 2
+`,
+	},
+	{
+		in: `title = "servers"
+
+# alpha server
+[servers.alpha]
+ip = "10.0.0.1"
+
+# beta server
+[servers.beta]
+ip = "10.0.0.2"
+
+[owner]
+name = "Tom"
+`,
+		edits: []documentEdit{{"servers.alpha", nil}, {"owner.name", nil}},
+		out: `title = "servers"
+
+# beta server
+[servers.beta]
+ip = "10.0.0.2"
+
+[owner]
+`,
+	},
+	{
+		in: `[a]
+x = 1
+
+[a.b]
+y = 2
+
+[c]
+z = 3
+`,
+		edits: []documentEdit{{"a", nil}},
+		out: `[c]
+z = 3
+`,
+	},
+	{
+		in: `[owner]
+name = "Tom"
+
+[other]
+`,
+		edits: []documentEdit{{"owner.server", newServerTable()}, {"server", newServerTable()}},
+		out: `[owner]
+name = "Tom"
+
+[owner.server]
+ip = "10.0.0.3"
+
+[other]
+
+[server]
+ip = "10.0.0.3"
+`,
+	},
+	{
+		in: `[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+
+[[products]]
+name = "Screw"
+`,
+		edits: []documentEdit{{"products[1]", nil}, {"products[1].sku", toml.Integer(284758393)}},
+		out: `[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Screw"
+sku = 284758393
+`,
+	},
+	{
+		in:    `[servers.alpha]`,
+		edits: []documentEdit{{"servers.beta", toml.String("10.0.0.2")}},
+		out: `[servers.alpha]
+
+[servers]
+beta = "10.0.0.2"
+`,
+	},
+	{
+		in: `[server]
+ip = "10.0.0.1"
+
+[other]
+`,
+		edits: []documentEdit{{"server", toml.String("10.0.0.1")}},
+		out: `server = "10.0.0.1"
+[other]
+`,
+	},
+	{
+		in:    ``,
+		edits: []documentEdit{{"products", toml.NewArray(newServerTable(), newServerTable())}},
+		out: `[[products]]
+ip = "10.0.0.3"
+
+[[products]]
+ip = "10.0.0.3"
+`,
+	},
+}
+
+func TestDocument(t *testing.T) {
+	for i, test := range documentTests {
+		doc, err := toml.ParseDocument([]byte(test.in))
+		if err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+		for _, edit := range test.edits {
+			if edit.value == nil {
+				err = doc.Delete(edit.path)
+			} else {
+				err = doc.Set(edit.path, edit.value)
+			}
+			if err != nil {
+				t.Errorf("#%d: edit %s: got error: %s", i, edit.path, err)
+				break
+			}
+		}
+		if got := doc.String(); got != test.out {
+			t.Errorf("#%d:\ngot:\n%s\nwant:\n%s", i, got, test.out)
+		}
+	}
+}
+
+func TestDocumentTable(t *testing.T) {
+	doc, err := toml.ParseDocument([]byte("a = 1"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if err := doc.Set("b", toml.String("2")); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := doc.Table().Get("b"); got != toml.String("2") {
+		t.Errorf("b: got %#v", got)
+	}
+}
+
+func TestDecoderParseDocument(t *testing.T) {
+	in := "a = 07:32\n"
+	if _, err := toml.ParseDocument([]byte(in)); err == nil {
+		t.Fatalf("%q: expect error in TOML %s", in, toml.DefaultVersion)
+	}
+	dec := toml.NewDecoder(strings.NewReader(in))
+	dec.SetVersion(toml.V1_1_0)
+	doc, err := dec.ParseDocument()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if err := doc.Set("b", toml.Integer(2)); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got, want := doc.String(), "a = 07:32\nb = 2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	dec = toml.NewDecoder(strings.NewReader("a = 1\n"))
+	dec.SetVersion(toml.V0_4_0)
+	doc, err = dec.ParseDocument()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	err = doc.Set("b", toml.Float(math.Inf(1)))
+	want := &toml.UnsupportedValueError{Value: "float inf", Version: toml.V0_4_0}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
+
+var documentErrorTests = []struct {
+	in   string
+	path string
+}{
+	{`a = 1`, "b"},
+	{`a = 1`, "b.c"},
+	{`a = 1`, "a.c"},
+	{`a = 1`, "a[0]"},
+	{`a = { b = 1 }`, "a.c"},
+	{`a = [ 1 ]`, "a[1]"},
+	{`[[a]]`, "a[1]"},
+	{`[[a]]`, "a.b"},
+	{`a = 1`, "*"},
+	{`a = 1`, "a..b"},
+}
+
+func TestDocumentDeleteError(t *testing.T) {
+	for _, test := range documentErrorTests {
+		doc, err := toml.ParseDocument([]byte(test.in))
+		if err != nil {
+			t.Errorf("%q: got error: %s", test.in, err)
+			continue
+		}
+		err = doc.Delete(test.path)
+		switch err.(type) {
+		case *toml.EditError, *toml.PathError:
+		default:
+			t.Errorf("%q: delete %s: got error %#v", test.in, test.path, err)
+		}
+		if got := doc.String(); got != test.in {
+			t.Errorf("%q: delete %s: document changed to %q", test.in, test.path, got)
+		}
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"go/ast"
 	"io"
+	"math"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +19,9 @@ import (
 
 type encodeState struct {
 	bytes.Buffer
+	version Version
+	keyLess func(a, b string) bool
+	naming  NamingStrategy
 }
 
 // InvalidMarshalError describes that invalid argument passed to Marshal.
@@ -69,6 +75,27 @@ func (e *MarshalArrayTypeError) Error() string {
 	return fmt.Sprintf("toml: array at %s expect element of type %s, got %s", e.Path, e.Expect, e.Got)
 }
 
+// UnsupportedValueError describes that a value is not supported in
+// specified version of TOML specification.
+type UnsupportedValueError struct {
+	Value   string
+	Version Version
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "toml: " + e.Value + " is not supported in TOML " + e.Version.String()
+}
+
+// InvalidNumberError describes that a Number is not a valid TOML integer
+// or float.
+type InvalidNumberError struct {
+	Number string
+}
+
+func (e *InvalidNumberError) Error() string {
+	return "toml: invalid number: " + strconv.Quote(e.Number)
+}
+
 // MarshalNilValueError describes that a nil pointer or interface in array or slice.
 type MarshalNilValueError struct {
 	Type reflect.Type
@@ -79,10 +106,70 @@ func (e *MarshalNilValueError) Error() string {
 	return "toml: cannot marshal nil value of Go type " + e.Type.String() + " as toml " + e.As
 }
 
-func indirectPtr(v reflect.Value) (encoding.TextMarshaler, reflect.Value) {
+// MarshalerError describes that MarshalTOML of a Go value returned an error.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+	Path string // key path of TOML value, empty for root table
+}
+
+func (e *MarshalerError) Error() string {
+	return "toml: " + errorLocation(e.Path, "", 0, 0) + "error calling MarshalTOML for Go value of type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error returned from MarshalTOML.
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+// Marshaler is the interface implemented by types that can marshal
+// themselves into TOML value. The returned value is encoded in place of
+// the original value, so it could be any value Marshal can encode, for
+// example, a slice for TOML array and a map for TOML table.
+type Marshaler interface {
+	MarshalTOML() (interface{}, error)
+}
+
+func indirectElem(v reflect.Value) reflect.Value {
 	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
 		v = v.Elem()
 	}
+	return v
+}
+
+func marshalerValue(v reflect.Value) (Marshaler, bool) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		return nil, false
+	}
+	if v.CanInterface() {
+		if m, ok := v.Interface().(Marshaler); ok {
+			return m, true
+		}
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(Marshaler); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// indirectPtr resolves v, which is at path, to value to encode and its
+// encoding.TextMarshaler if any. Marshaler is called at most once.
+func indirectPtr(path string, v reflect.Value) (encoding.TextMarshaler, reflect.Value) {
+	v = indirectElem(v)
+	if m, ok := marshalerValue(v); ok {
+		result, err := m.MarshalTOML()
+		if err != nil {
+			panic(&MarshalerError{Type: v.Type(), Err: err, Path: path})
+		}
+		v = indirectElem(reflect.ValueOf(&result).Elem())
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		// Nil pointers, say, nil *big.Int, are omitted in table and
+		// rejected in array, other than encoded by MarshalText.
+		return nil, v
+	}
 	if v.CanInterface() {
 		if i, ok := v.Interface().(encoding.TextMarshaler); ok {
 			return i, v
@@ -100,6 +187,22 @@ func indirectPtr(v reflect.Value) (encoding.TextMarshaler, reflect.Value) {
 type field struct {
 	key   string
 	value reflect.Value
+	elems []element // resolved elements of array of tables
+}
+
+// element is an array element resolved by indirectPtr.
+type element struct {
+	ti    encoding.TextMarshaler
+	value reflect.Value
+}
+
+func resolveElems(path string, v reflect.Value) []element {
+	elems := make([]element, v.Len())
+	for i := range elems {
+		ti, elem := indirectPtr(combineIndexPath(path, i), v.Index(i))
+		elems[i] = element{ti, elem}
+	}
+	return elems
 }
 
 type table struct {
@@ -143,19 +246,52 @@ func (t *table) recordKey(key string) {
 
 func (t *table) appendStructField(key string, value reflect.Value) {
 	t.recordKey(key)
-	t.tables = append(t.tables, field{key, value})
+	t.tables = append(t.tables, field{key: key, value: value})
+}
+
+func (t *table) appendTableArray(key string, elems []element) {
+	t.recordKey(key)
+	t.tables = append(t.tables, field{key: key, elems: elems})
 }
 
 var (
-	datetimeType = reflect.TypeOf((*time.Time)(nil)).Elem()
+	datetimeType      = reflect.TypeOf((*time.Time)(nil)).Elem()
+	localDateType     = reflect.TypeOf(LocalDate{})
+	localTimeType     = reflect.TypeOf(LocalTime{})
+	localDateTimeType = reflect.TypeOf(LocalDateTime{})
+	durationType      = reflect.TypeOf(time.Duration(0))
 )
 
+func isNumberType(t reflect.Type) bool {
+	return t == numberType || t == bigIntType || t == bigFloatType
+}
+
+func localTypeName(t reflect.Type) string {
+	switch t {
+	case localDateType:
+		return "local date"
+	case localTimeType:
+		return "local time"
+	case localDateTimeType:
+		return "local datetime"
+	}
+	return ""
+}
+
 type stringValues []reflect.Value
 
 func (sv stringValues) Len() int           { return len(sv) }
 func (sv stringValues) Swap(i, j int)      { sv[i], sv[j] = sv[j], sv[i] }
 func (sv stringValues) Less(i, j int) bool { return sv[i].String() < sv[j].String() }
 
+func (e *encodeState) sortKeys(keys []reflect.Value) {
+	if e.keyLess == nil {
+		sort.Sort(stringValues(keys))
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool { return e.keyLess(keys[i].String(), keys[j].String()) })
+}
+
 func (e *encodeState) WriteSepKeyAssign(sep, key string) {
 	e.WriteString(sep)
 	e.WriteString(normalizeKey(key))
@@ -340,11 +476,76 @@ func (e *encodeState) marshalUintValue(u uint64, options tagOptions) {
 	e.marshalRawValue(strconv.FormatUint(u, 10), options)
 }
 
-func (e *encodeState) marshalFloatValue(f float64, options tagOptions) {
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
 	s := strconv.FormatFloat(f, 'g', -1, 64)
 	if strings.IndexAny(s, ".e") == -1 {
 		s += ".0"
 	}
+	return s
+}
+
+func (e *encodeState) marshalFloatValue(f float64, options tagOptions) {
+	s := formatFloat(f)
+	if e.version < V1_0_0 && (math.IsInf(f, 0) || math.IsNaN(f)) {
+		panic(&UnsupportedValueError{Value: "float " + s, Version: e.version})
+	}
+	e.marshalRawValue(s, options)
+}
+
+// numberText returns text and TOML type of v, which is of type Number,
+// big.Int or big.Float.
+func (e *encodeState) numberText(v reflect.Value) (string, string) {
+	if v.Type() == numberType {
+		s := v.String()
+		typ, since, ok := checkNumber(s)
+		if !ok {
+			panic(&InvalidNumberError{Number: s})
+		}
+		if e.version < since {
+			panic(&UnsupportedValueError{Value: typ + " " + s, Version: e.version})
+		}
+		return s, typ
+	}
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	switch x := v.Addr().Interface().(type) {
+	case *big.Int:
+		return x.String(), "integer"
+	case *big.Float:
+		if x.IsInf() {
+			s := "inf"
+			if x.Signbit() {
+				s = "-inf"
+			}
+			if e.version < V1_0_0 {
+				panic(&UnsupportedValueError{Value: "float " + s, Version: e.version})
+			}
+			return s, "float"
+		}
+		s := x.Text('g', -1)
+		if strings.IndexAny(s, ".e") == -1 {
+			s += ".0"
+		}
+		return s, "float"
+	}
+	panic(&MarshalTypeError{Type: v.Type(), As: "number"})
+}
+
+func (e *encodeState) marshalNumberField(t *table, key string, v reflect.Value, options tagOptions) {
+	s, _ := e.numberText(v)
+	t.recordKey(key)
+	e.WriteSepKeyAssign(t.fieldSep(), key)
 	e.marshalRawValue(s, options)
 }
 
@@ -390,6 +591,20 @@ func (e *encodeState) marshalDatetimeField(t *table, key string, value reflect.V
 	e.marshalDatetimeValue(value, options)
 }
 
+func (e *encodeState) marshalLocalValue(value reflect.Value, options tagOptions) {
+	s := value.Interface().(fmt.Stringer).String()
+	if e.version < V1_0_0 {
+		panic(&UnsupportedValueError{Value: localTypeName(value.Type()) + " " + s, Version: e.version})
+	}
+	e.marshalRawValue(s, options)
+}
+
+func (e *encodeState) marshalLocalField(t *table, key string, value reflect.Value, options tagOptions) {
+	t.recordKey(key)
+	e.WriteSepKeyAssign(t.fieldSep(), key)
+	e.marshalLocalValue(value, options)
+}
+
 func (e *encodeState) marshalTextField(t *table, key string, ti encoding.TextMarshaler, options tagOptions) {
 	t.recordKey(key)
 	e.WriteSepKeyAssign(t.fieldSep(), key)
@@ -422,8 +637,14 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
-func checkArrayElemType(path string, elemType string) func(newElemType string) {
+// checkArrayElemType returns a function to check that elements of array
+// are of same type, which is required prior to TOML v1.0.0.
+func (e *encodeState) checkArrayElemType(path string) func(newType string) {
+	elemType := ""
 	return func(newType string) {
+		if e.version >= V1_0_0 {
+			return
+		}
 		if elemType == "" {
 			elemType = newType
 		} else if elemType != newType {
@@ -436,28 +657,62 @@ func isTableType(typ reflect.Type) bool {
 	return typ.Kind() == reflect.Map || typ.Kind() == reflect.Struct
 }
 
-func (e *encodeState) marshalArrayValue(path string, v reflect.Value, options tagOptions) string {
-	if v.Type().Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+func isBytesType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// isTableArray reports whether elems is a non-empty array of tables.
+func isTableArray(elems []element) bool {
+	if len(elems) == 0 {
+		return false
+	}
+	for _, elem := range elems {
+		if elem.ti != nil || !isTableType(elem.value.Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *encodeState) marshalArrayValue(path string, v reflect.Value, options tagOptions) {
+	if isBytesType(v.Type()) {
 		e.marshalBytesValue(v.Bytes(), options)
-		return "string"
+		return
 	}
+	e.marshalArrayElems(path, resolveElems(path, v), options)
+}
 
+func (e *encodeState) marshalArrayElems(path string, elems []element, options tagOptions) {
 	sep := " "
 	e.WriteByte('[')
-	check := checkArrayElemType(path, "")
-	for i, n := 0, v.Len(); i < n; i++ {
+	check := e.checkArrayElemType(path)
+	for i, x := range elems {
 		e.WriteString(sep)
-		ti, elem := indirectPtr(v.Index(i))
+		sep = ", "
+		ti, elem := x.ti, x.value
 		switch {
+		case isNumberType(elem.Type()):
+			s, typ := e.numberText(elem)
+			check(typ)
+			e.marshalRawValue(s, options)
+			continue
 		case elem.Type() == datetimeType,
 			elem.Type().ConvertibleTo(datetimeType) && options.Has("datetime"):
 			check("datetime")
 			e.marshalDatetimeValue(elem, options)
 			continue
+		case localTypeName(elem.Type()) != "":
+			check(localTypeName(elem.Type()))
+			e.marshalLocalValue(elem, options)
+			continue
 		case ti != nil:
 			check("string")
 			e.marshalTextValue(ti, options)
 			continue
+		case elem.Type() == durationType:
+			check("string")
+			e.marshalStringValue(time.Duration(elem.Int()).String(), options)
+			continue
 		}
 		switch elem.Kind() {
 		case reflect.Bool:
@@ -476,7 +731,12 @@ func (e *encodeState) marshalArrayValue(path string, v reflect.Value, options ta
 			check("string")
 			e.marshalStringValue(elem.String(), options)
 		case reflect.Array, reflect.Slice:
-			check(e.marshalArrayValue(combineIndexPath(path, i), elem, options))
+			if isBytesType(elem.Type()) {
+				check("string")
+			} else {
+				check("array")
+			}
+			e.marshalArrayValue(combineIndexPath(path, i), elem, options)
 		case reflect.Map:
 			check("table")
 			e.marshalMapValue(combineIndexPath(path, i), elem, options)
@@ -488,33 +748,44 @@ func (e *encodeState) marshalArrayValue(path string, v reflect.Value, options ta
 		default:
 			panic(&MarshalTypeError{Type: elem.Type(), As: "array element"})
 		}
-		sep = ", "
 	}
 	e.WriteString(" ]")
-	return "array"
 }
 
 func (e *encodeState) marshalArrayField(t *table, key string, v reflect.Value, options tagOptions) {
-	if v.Len() != 0 {
-		ti, elem := indirectPtr(v.Index(0))
-		if ti == nil && isTableType(elem.Type()) && !options.Has("inline") {
-			t.appendStructField(key, v)
-			return
-		}
+	path := combineKeyPath(t.Path, key)
+	if isBytesType(v.Type()) {
+		t.recordKey(key)
+		e.WriteSepKeyAssign(t.fieldSep(), key)
+		e.marshalBytesValue(v.Bytes(), options)
+		return
+	}
+	elems := resolveElems(path, v)
+	if !t.Inline && !options.Has("inline") && isTableArray(elems) {
+		t.appendTableArray(key, elems)
+		return
 	}
 	t.recordKey(key)
 	e.WriteSepKeyAssign(t.fieldSep(), key)
-	e.marshalArrayValue(combineKeyPath(t.Path, key), v, options)
+	e.marshalArrayElems(path, elems, options)
 }
 
 func (e *encodeState) marshalTableField(t *table, key string, v reflect.Value, options tagOptions) {
-	ti, v := indirectPtr(v)
+	ti, v := indirectPtr(combineKeyPath(t.Path, key), v)
 
 	switch {
+	case isNumberType(v.Type()):
+		if !options.Has("omitempty") || !isEmptyValue(v) {
+			e.marshalNumberField(t, key, v, options)
+		}
+		return
 	case v.Type() == datetimeType,
 		v.Type().ConvertibleTo(datetimeType) && options.Has("datetime"):
 		e.marshalDatetimeField(t, key, v, options)
 		return
+	case localTypeName(v.Type()) != "":
+		e.marshalLocalField(t, key, v, options)
+		return
 	case ti != nil:
 		e.marshalTextField(t, key, ti, options)
 		return
@@ -524,6 +795,11 @@ func (e *encodeState) marshalTableField(t *table, key string, v reflect.Value, o
 		return
 	}
 
+	if v.Type() == durationType {
+		e.marshalStringField(t, key, time.Duration(v.Int()).String(), options)
+		return
+	}
+
 	switch v.Kind() {
 	case reflect.Bool:
 		e.marshalBoolField(t, key, v.Bool(), options)
@@ -559,7 +835,8 @@ func (e *encodeState) marshalMapValue(path string, v reflect.Value, options tagO
 		panic(&MarshalTypeError{Type: v.Type(), As: "table key"})
 	}
 	e.WriteByte('{')
-	var keys stringValues = v.MapKeys()
+	keys := v.MapKeys()
+	e.sortKeys(keys)
 	t := &table{Inline: true, Type: v.Type(), sep: " "}
 	for _, k := range keys {
 		e.marshalTableField(t, k.String(), v.MapIndex(k), nil)
@@ -586,7 +863,19 @@ func (e *encodeState) marshalStructField(t *table, key string, v reflect.Value)
 	e.marshalStructValue(combineKeyPath(t.Path, key), v, nil)
 }
 
+// marshalTreeTable encodes values of document tree table tt in order of
+// its keys.
+func (e *encodeState) marshalTreeTable(t *table, tt Table) {
+	for _, key := range tt.keys {
+		e.marshalTableField(t, key, reflect.ValueOf(treeInterface(tt.elems[key])), nil)
+	}
+}
+
 func (e *encodeState) marshalStructTable(t *table, v reflect.Value) {
+	if v.Type() == tableType {
+		e.marshalTreeTable(t, v.Interface().(Table))
+		return
+	}
 	for i := 0; i < v.NumField(); i++ {
 		sf := v.Type().Field(i)
 		name, options := parseTag(sf.Tag.Get("toml"))
@@ -614,6 +903,9 @@ func (e *encodeState) marshalStructTable(t *table, v reflect.Value) {
 		}
 		if name == "" {
 			name = sf.Name
+			if e.naming != nil {
+				name = e.naming(name)
+			}
 		}
 		e.marshalTableField(t, name, v.Field(i), options)
 	}
@@ -621,8 +913,12 @@ func (e *encodeState) marshalStructTable(t *table, v reflect.Value) {
 
 func (e *encodeState) marshalTables(sup *table, tables []field) {
 	for _, f := range tables {
-		v := f.value
 		path := combineKeyPath(sup.Path, f.key)
+		if f.elems != nil {
+			e.marshalTableArray(sup, path, f.elems)
+			continue
+		}
+		v := f.value
 		switch v.Type().Kind() {
 		case reflect.Map:
 			e.WriteString(fmt.Sprintf("%s[%s]", sup.tableSep(), path))
@@ -631,29 +927,32 @@ func (e *encodeState) marshalTables(sup *table, tables []field) {
 			e.WriteString(fmt.Sprintf("%s[%s]", sup.tableSep(), path))
 			e.marshalStruct(path, v)
 		case reflect.Array, reflect.Slice:
-			for i, n := 0, v.Len(); i < n; i++ {
-				e.WriteString(fmt.Sprintf("%s[[%s]]", sup.tableSep(), path))
-				ti, elem := indirectPtr(v.Index(i))
-				if ti != nil {
-					panic(&MarshalTypeError{Type: elem.Type(), As: "table"})
-				}
-				switch elem.Type().Kind() {
-				case reflect.Map:
-					e.marshalMap(path, elem)
-				case reflect.Struct:
-					e.marshalStruct(path, elem)
-				case reflect.Ptr, reflect.Interface:
-					panic(&MarshalNilValueError{Type: elem.Type(), As: "array element"})
-				default:
-					panic(&MarshalTypeError{Type: elem.Type(), As: "table"})
-				}
-			}
+			e.marshalTableArray(sup, path, resolveElems(path, v))
 		default:
 			panic("toml: unexpected postponed field")
 		}
 	}
 }
 
+func (e *encodeState) marshalTableArray(sup *table, path string, elems []element) {
+	for _, elem := range elems {
+		e.WriteString(fmt.Sprintf("%s[[%s]]", sup.tableSep(), path))
+		if elem.ti != nil {
+			panic(&MarshalTypeError{Type: elem.value.Type(), As: "table"})
+		}
+		switch elem.value.Kind() {
+		case reflect.Map:
+			e.marshalMap(path, elem.value)
+		case reflect.Struct:
+			e.marshalStruct(path, elem.value)
+		case reflect.Ptr, reflect.Interface:
+			panic(&MarshalNilValueError{Type: elem.value.Type(), As: "array element"})
+		default:
+			panic(&MarshalTypeError{Type: elem.value.Type(), As: "table"})
+		}
+	}
+}
+
 func (e *encodeState) marshalMap(path string, v reflect.Value) {
 	if v.Type().Key().Kind() != reflect.String {
 		panic(&MarshalTypeError{Type: v.Type(), As: "table key"})
@@ -662,7 +961,8 @@ func (e *encodeState) marshalMap(path string, v reflect.Value) {
 	if path == "" {
 		t.sep = ""
 	}
-	var keys stringValues = v.MapKeys()
+	keys := v.MapKeys()
+	e.sortKeys(keys)
 	for _, k := range keys {
 		e.marshalTableField(t, k.String(), v.MapIndex(k), nil)
 	}
@@ -679,7 +979,7 @@ func (e *encodeState) marshalStruct(path string, v reflect.Value) {
 }
 
 func validMarshal(v interface{}) (reflect.Value, error) {
-	ti, rv := indirectPtr(reflect.ValueOf(v))
+	ti, rv := indirectPtr("", reflect.ValueOf(v))
 	if ti != nil {
 		return reflect.Value{}, &MarshalTypeError{Type: reflect.TypeOf(v), As: "table"}
 	}
@@ -698,7 +998,15 @@ func validMarshal(v interface{}) (reflect.Value, error) {
 // Argument v must be of type struct/map or non-nil pointer or interface
 // to these types and must not implement encoding.TextMarshaler.
 //
-// Values implementing encoding.TextMarshaler are encoded as strings.
+// Values implementing Marshaler are encoded as what their MarshalTOML
+// return, errors from MarshalTOML are reported in MarshalerError. Otherwise, values implementing encoding.TextMarshaler are
+// encoded as strings.
+//
+// Keys of maps are sorted in increasing order, struct fields are encoded in
+// declaration order.
+//
+// Struct fields are keyed by their tagged names, or their field names, see
+// Encoder.SetNamingStrategy.
 //
 // Fields with nil value in struct or map are ignored. Nil maps or
 // slices in array are encoded as empty tables or arrays in TOML. Error
@@ -708,7 +1016,17 @@ func validMarshal(v interface{}) (reflect.Value, error) {
 // Slice of byte is encoded as base64-encoded string.
 //
 // time.Time and types with "datetime" tagged and convertible to
-// time.Time are encoded as TOML Datetime.
+// time.Time are encoded as TOML Datetime. LocalDate, LocalTime and
+// LocalDateTime are encoded as TOML Local Date, Local Time and Local
+// Date-Time. time.Duration is encoded as string, say, "1m30s".
+//
+// big.Int and big.Float are encoded as TOML Integer and Float, though
+// they implement encoding.TextMarshaler. Number is encoded as TOML number
+// in its text, InvalidNumberError is raised if the text is not a valid
+// TOML integer or float.
+//
+// Document tree values, see Value, are encoded as TOML values they
+// represent. Keys of Table are encoded in order, see Table.Keys.
 //
 // Any value that will be encoded as string can have "literal",
 // "multiline" and/or "ascii" tagged.
@@ -717,15 +1035,22 @@ func validMarshal(v interface{}) (reflect.Value, error) {
 //
 // Tag options specified for array or slice fields are inherited by their
 // elements.
-func Marshal(v interface{}) (b []byte, err error) {
+//
+// Marshal encodes v in DefaultVersion of TOML specification. Use Encoder
+// to encode in other versions.
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encodeState{version: DefaultVersion}
+	return e.marshal(v)
+}
+
+func (e *encodeState) marshal(v interface{}) (b []byte, err error) {
+	defer catchError(&err)
+
 	rv, err := validMarshal(v)
 	if err != nil {
 		return nil, err
 	}
 
-	defer catchError(&err)
-
-	var e encodeState
 	switch rv.Kind() {
 	case reflect.Map:
 		e.marshalMap("", rv)
@@ -738,13 +1063,37 @@ func Marshal(v interface{}) (b []byte, err error) {
 
 // Encoder writes TOML document to an output stream.
 type Encoder struct {
-	w   io.Writer
-	err error
+	w       io.Writer
+	err     error
+	version Version
+	keyLess func(a, b string) bool
+	naming  NamingStrategy
 }
 
 // NewEncoder creates a new encoder that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w: w}
+	return &Encoder{w: w, version: DefaultVersion}
+}
+
+// SetVersion sets version of TOML specification the encoder conforms to.
+// Values not supported in that version are reported as
+// UnsupportedValueError.
+func (enc *Encoder) SetVersion(v Version) {
+	enc.version = v.normalize()
+}
+
+// SetKeyOrder sets function less to sort keys of maps. Keys are sorted
+// in increasing order by default. Nil less restores the default.
+func (enc *Encoder) SetKeyOrder(less func(a, b string) bool) {
+	enc.keyLess = less
+}
+
+// SetNamingStrategy sets strategy to convert names of struct fields without
+// tagged names to TOML keys. Field names are used as is by default. Nil
+// strategy restores the default. Use LowerCase to encode keys in lower
+// case, as what Unmarshal accepts.
+func (enc *Encoder) SetNamingStrategy(naming NamingStrategy) {
+	enc.naming = naming
 }
 
 // Encode writes TOML document of v to the underlying stream.
@@ -753,7 +1102,8 @@ func (enc *Encoder) Encode(v interface{}) error {
 		return enc.err
 	}
 
-	b, err := Marshal(v)
+	e := &encodeState{version: enc.version, keyLess: enc.keyLess, naming: enc.naming}
+	b, err := e.marshal(v)
 	if err != nil {
 		return err
 	}
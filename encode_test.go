@@ -1,6 +1,11 @@
 package toml_test
 
 import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -44,6 +49,21 @@ type EncodeStruct struct {
 	Inlines []EncodeTable  `toml:",inline"`
 }
 
+type EncodeFloats struct {
+	Inf     float64
+	NegInf  float64
+	Float32 float32
+	Floats  []float64
+}
+
+type EncodeLocalDatetimes struct {
+	Date      toml.LocalDate
+	Time      toml.LocalTime
+	DateTime  toml.LocalDateTime
+	Times     []toml.LocalTime
+	DateTimes []toml.LocalDateTime
+}
+
 type encodeData struct {
 	in    interface{}
 	out   interface{}
@@ -113,6 +133,41 @@ var marshalTests = []encodeData{
 		},
 		out: &map[string]interface{}{},
 	},
+	{
+		in: map[string]interface{}{
+			"mixed": []interface{}{int64(1), "two", map[string]interface{}{"x": int64(3)}},
+			"tables": []interface{}{
+				map[string]interface{}{"name": "table"},
+				[]interface{}{"not", "table"},
+			},
+		},
+		out: &map[string]interface{}{},
+	},
+	{
+		in: Unmarshalers{
+			Timeout: Seconds(90 * time.Second),
+			Tags:    StringSet{"b": {}, "a": {}},
+		},
+		out: new(Unmarshalers),
+	},
+	{
+		in: EncodeFloats{
+			Inf:     math.Inf(1),
+			NegInf:  math.Inf(-1),
+			Float32: float32(math.Inf(1)),
+			Floats:  []float64{math.Inf(-1), 0, math.Inf(1)},
+		},
+		out: new(EncodeFloats),
+	},
+	{
+		in: EncodeLocalDatetimes{
+			Date:     toml.LocalDate{2016, time.January, 7},
+			Time:     toml.LocalTime{15, 30, 30, 123456789},
+			DateTime: toml.LocalDateTime{toml.LocalDate{2016, time.January, 7}, toml.LocalTime{15, 30, 30, 5e8}},
+			Times:    []toml.LocalTime{{0, 0, 0, 0}, {23, 59, 59, 0}},
+		},
+		out: new(EncodeLocalDatetimes),
+	},
 }
 
 func TestMarshal(t *testing.T) {
@@ -148,3 +203,419 @@ func TestMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshaler(t *testing.T) {
+	b, err := toml.Marshal(Unmarshalers{Timeout: Seconds(time.Second), Tags: StringSet{"b": {}, "a": {}}})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := "Timeout = \"1s\"\nTags = [ \"a\", \"b\" ]\n"
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}
+
+// countedTable marshals itself as table numbering calls to MarshalTOML.
+type countedTable struct {
+	n *int
+}
+
+func (t countedTable) MarshalTOML() (interface{}, error) {
+	*t.n++
+	if *t.n > 2 {
+		return nil, errors.New("too many calls")
+	}
+	return map[string]int{"n": *t.n}, nil
+}
+
+func TestMarshalerCalledOnce(t *testing.T) {
+	n := 0
+	b, err := toml.Marshal(map[string]interface{}{"a": []countedTable{{&n}, {&n}}})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := "[[a]]\nn = 1\n\n[[a]]\nn = 2\n"
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	n = 1
+	_, err = toml.Marshal(map[string]interface{}{"a": []countedTable{{&n}, {&n}}})
+	wantErr := &toml.MarshalerError{Type: reflect.TypeOf(countedTable{}), Err: errors.New("too many calls"), Path: "a[1]"}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+
+	n = 2
+	_, err = toml.Marshal(countedTable{&n})
+	wantErr = &toml.MarshalerError{Type: reflect.TypeOf(countedTable{}), Err: errors.New("too many calls")}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+}
+
+func TestMarshalNaN(t *testing.T) {
+	in := struct{ F float64 }{math.NaN()}
+	b, err := toml.Marshal(in)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if string(b) != "F = nan\n" {
+		t.Fatalf("got %q, want %q", b, "F = nan\n")
+	}
+	var out struct{ F float64 }
+	if err := toml.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal error: %s", err)
+	}
+	if !math.IsNaN(out.F) {
+		t.Fatalf("got %v, want NaN", out.F)
+	}
+}
+
+var encodeVersionTests = []struct {
+	in      interface{}
+	version toml.Version
+	out     string
+	err     error
+}{
+	{
+		in:      map[string]interface{}{"a": []interface{}{1, "a"}},
+		version: toml.V0_4_0,
+		err:     &toml.MarshalArrayTypeError{Path: "a", Expect: "integer", Got: "string"},
+	},
+	{
+		in:      struct{ F float64 }{math.Inf(-1)},
+		version: toml.V0_4_0,
+		err:     &toml.UnsupportedValueError{Value: "float -inf", Version: toml.V0_4_0},
+	},
+	{
+		in:      struct{ D toml.LocalDate }{toml.LocalDate{2016, time.January, 7}},
+		version: toml.V0_4_0,
+		err:     &toml.UnsupportedValueError{Value: "local date 2016-01-07", Version: toml.V0_4_0},
+	},
+	{
+		in:      map[string]interface{}{"a": []interface{}{1, "a"}},
+		version: toml.V1_0_0,
+		out:     "a = [ 1, \"a\" ]\n",
+	},
+}
+
+func TestEncoderVersion(t *testing.T) {
+	for i, test := range encodeVersionTests {
+		var buf bytes.Buffer
+		enc := toml.NewEncoder(&buf)
+		enc.SetVersion(test.version)
+		err := enc.Encode(test.in)
+
+		if test.err != nil {
+			if !reflect.DeepEqual(test.err, err) {
+				t.Errorf("#%d: error got %s\n, want %s", i, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+
+		if got := buf.String(); got != test.out {
+			t.Errorf("#%d: got %q, want %q", i, got, test.out)
+		}
+	}
+}
+
+func TestMarshalDuration(t *testing.T) {
+	in := struct {
+		Timeout time.Duration
+		Zero    time.Duration `toml:",omitempty"`
+		Retries []time.Duration
+		Ptr     *time.Duration
+	}{
+		Timeout: 90 * time.Second,
+		Retries: []time.Duration{time.Second, -2500 * time.Microsecond},
+	}
+	b, err := toml.Marshal(in)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := "Timeout = \"1m30s\"\nRetries = [ \"1s\", \"-2.5ms\" ]\n"
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}
+
+func TestMarshalBigNumbers(t *testing.T) {
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	in := struct {
+		Max    uint64
+		Int    *big.Int
+		Float  big.Float
+		Inf    *big.Float
+		Nil    *big.Int
+		Number toml.Number
+		Empty  toml.Number `toml:",omitempty"`
+		Array  []interface{}
+	}{
+		Max:    math.MaxUint64,
+		Int:    bigInt,
+		Float:  *big.NewFloat(2),
+		Inf:    big.NewFloat(math.Inf(-1)),
+		Number: "0x10",
+		Array:  []interface{}{big.NewInt(1), toml.Number("99999999999999999999")},
+	}
+	b, err := toml.Marshal(in)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := `Max = 18446744073709551615
+Int = 123456789012345678901234567890
+Float = 2.0
+Inf = -inf
+Number = 0x10
+Array = [ 1, 99999999999999999999 ]
+`
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	// Exponents written by big.Float and in Number are parsed back.
+	type exponents struct {
+		Float  *big.Float
+		Number toml.Number
+	}
+	in2 := exponents{big.NewFloat(1e6), "1e+06"}
+	b, err = toml.Marshal(in2)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if want := "Float = 1e+06\nNumber = 1e+06\n"; string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+	var out2 exponents
+	if err := toml.Unmarshal(b, &out2); err != nil {
+		t.Fatalf("unmarshal error: %s", err)
+	}
+	if out2.Float.Cmp(in2.Float) != 0 || out2.Number != in2.Number {
+		t.Errorf("got %+v, want %+v", out2, in2)
+	}
+
+	for _, v := range []interface{}{[]*big.Int{nil}, []interface{}{(*big.Float)(nil)}} {
+		_, err := toml.Marshal(map[string]interface{}{"a": v})
+		if _, ok := err.(*toml.MarshalNilValueError); !ok {
+			t.Errorf("%#v: got error %#v, want *toml.MarshalNilValueError", v, err)
+		}
+	}
+
+	for _, n := range []toml.Number{"", "1.", "1e", ".5", "01", "01.5", "00e1", "1__0", "1_", "-0x10", "0x", "0xg", "abc", "1 # comment", "1\nb = 2", "'1'"} {
+		_, err := toml.Marshal(map[string]toml.Number{"n": n})
+		if _, ok := err.(*toml.InvalidNumberError); !ok {
+			t.Errorf("%q: got error %#v, want *toml.InvalidNumberError", n, err)
+		}
+	}
+
+	for _, test := range []struct {
+		n   toml.Number
+		err error
+	}{
+		{"1_000", nil},
+		{"-1.5e+10", nil},
+		{"0x10", &toml.UnsupportedValueError{Value: "integer 0x10", Version: toml.V0_4_0}},
+		{"-inf", &toml.UnsupportedValueError{Value: "float -inf", Version: toml.V0_4_0}},
+	} {
+		enc := toml.NewEncoder(new(bytes.Buffer))
+		enc.SetVersion(toml.V0_4_0)
+		err := enc.Encode(map[string]toml.Number{"n": test.n})
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("%s: got error %v, want %v", test.n, err, test.err)
+		}
+	}
+}
+
+func TestMarshalMapKeyOrder(t *testing.T) {
+	in := map[string]interface{}{
+		"b": 2,
+		"a": 1,
+		"c": map[string]int{"z": 26, "y": 25, "x": 24},
+		"d": struct {
+			Inline map[string]int `toml:",inline"`
+		}{map[string]int{"2": 2, "1": 1}},
+	}
+	want := `a = 1
+b = 2
+
+[c]
+x = 24
+y = 25
+z = 26
+
+[d]
+Inline = { 1 = 1, 2 = 2}
+`
+	for i := 0; i < 10; i++ {
+		b, err := toml.Marshal(in)
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		if string(b) != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", b, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.SetKeyOrder(func(a, b string) bool { return a > b })
+	if err := enc.Encode(map[string]int{"a": 1, "c": 3, "b": 2}); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got, want := buf.String(), "c = 3\nb = 2\na = 1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type RoundTripNested struct {
+	Name  string
+	Ports []uint16
+}
+
+type RoundTripEmbed struct {
+	EmbedID int64
+}
+
+type RoundTripAll struct {
+	Bool      bool
+	Int       int
+	Int8      int8
+	Int16     int16
+	Int32     int32
+	Int64     int64
+	Uint      uint
+	Uint8     uint8
+	Uint16    uint16
+	Uint32    uint32
+	Uint64    uint64
+	Float32   float32
+	Float64   float64
+	Floats    []float64
+	String    string
+	Multiline string `toml:",multiline"`
+	Literal   string `toml:",literal"`
+	Quoted    int    `toml:",string"`
+	Bytes     []byte
+	Timestamp time.Time
+	Date      toml.LocalDate
+	Time      toml.LocalTime
+	DateTime  toml.LocalDateTime
+	IP        net.IP
+	Duration  Seconds
+	Interval  time.Duration
+	Set       StringSet
+	Ptr       *int
+	Array     [3]int
+	Slice     []string
+	Nested    [][]int
+	Mixed     []interface{}
+	Any       interface{}
+	Map       map[string]int
+	Table     RoundTripNested
+	TablePtr  *RoundTripNested
+	Tables    []RoundTripNested
+	Inline    RoundTripNested `toml:",inline"`
+	MapTables map[string]RoundTripNested
+	Tree      *toml.Table
+	Value     toml.Value
+	RoundTripEmbed
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	n := 42
+	tree := toml.NewTable()
+	tree.Set("k", toml.String("v"))
+	in := RoundTripAll{
+		Bool: true, Int: -1, Int8: math.MinInt8, Int16: math.MaxInt16, Int32: math.MinInt32, Int64: math.MaxInt64,
+		Uint: 1, Uint8: math.MaxUint8, Uint16: math.MaxUint16, Uint32: math.MaxUint32, Uint64: math.MaxUint64,
+		Float32: 1.5, Float64: -2.25e-300, Floats: []float64{1e21, 1e-7, 1e6, 123456789},
+		String: "tab\t\"quote\" \u00e9 \x01", Multiline: "line1\nline2\n", Literal: `C:\path`, Quoted: 7,
+		Bytes:          []byte{0, 1, 2, 255},
+		Timestamp:      time.Date(1979, 5, 27, 7, 32, 0, 999999000, time.FixedZone("", -7*3600)),
+		Date:           toml.LocalDate{Year: 1979, Month: 5, Day: 27},
+		Time:           toml.LocalTime{Hour: 7, Minute: 32, Second: 1, Nanosecond: 500},
+		DateTime:       toml.LocalDateTime{Date: toml.LocalDate{Year: 1979, Month: 5, Day: 27}, Time: toml.LocalTime{Hour: 7}},
+		IP:             net.ParseIP("10.0.0.1"),
+		Interval:       1500 * time.Millisecond,
+		Duration:       Seconds(3 * time.Second),
+		Set:            StringSet{"a": {}, "b": {}},
+		Ptr:            &n,
+		Array:          [3]int{1, 2, 3},
+		Slice:          []string{"a", "b"},
+		Nested:         [][]int{{1}, {2, 3}},
+		Mixed:          []interface{}{int64(1), "two", 3.5},
+		Any:            map[string]interface{}{"x": int64(1)},
+		Map:            map[string]int{"b": 2, "a": 1, "with space": 3},
+		Table:          RoundTripNested{"t", []uint16{80}},
+		TablePtr:       &RoundTripNested{Name: "p"},
+		Tables:         []RoundTripNested{{Name: "a"}, {Name: "b", Ports: []uint16{1, 2}}},
+		Inline:         RoundTripNested{"i", []uint16{}},
+		MapTables:      map[string]RoundTripNested{"x": {Name: "x"}},
+		Tree:           tree,
+		Value:          toml.Integer(5),
+		RoundTripEmbed: RoundTripEmbed{EmbedID: 9},
+	}
+	b, err := toml.Marshal(in)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	var out RoundTripAll
+	if err := toml.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal error: %s\ntext:\n%s", err, b)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got  %+v,\nwant %+v\n,\ntext:\n%s", out, in, b)
+	}
+
+	// big.Float is encoded in shortest text of its precision, which is
+	// kept in decoding into big.Float of the same precision.
+	bigIn := struct{ Float *big.Float }{new(big.Float).SetMantExp(big.NewFloat(-1.5), 5000)}
+	b, err = toml.Marshal(bigIn)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	bigOut := struct{ Float *big.Float }{new(big.Float).SetPrec(bigIn.Float.Prec())}
+	if err := toml.Unmarshal(b, &bigOut); err != nil {
+		t.Fatalf("unmarshal error: %s\ntext:\n%s", err, b)
+	}
+	if bigOut.Float.Cmp(bigIn.Float) != 0 {
+		t.Errorf("got %s, want %s", bigOut.Float.Text('g', -1), bigIn.Float.Text('g', -1))
+	}
+
+	// Keys in lower case are accepted in decoding by default.
+	namings := []struct {
+		encode toml.NamingStrategy
+		decode toml.NamingStrategy
+	}{
+		{nil, nil},
+		{toml.LowerCase, nil},
+		{toml.SnakeCase, toml.SnakeCase},
+		{toml.KebabCase, toml.KebabCase},
+		{toml.CamelCase, toml.CamelCase},
+	}
+	for i, naming := range namings {
+		var buf bytes.Buffer
+		enc := toml.NewEncoder(&buf)
+		enc.SetNamingStrategy(naming.encode)
+		if err := enc.Encode(in); err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+		dec := toml.NewDecoder(bytes.NewReader(buf.Bytes()))
+		dec.SetNamingStrategy(naming.decode)
+		var out RoundTripAll
+		if err := dec.Decode(&out); err != nil {
+			t.Errorf("#%d: unmarshal error: %s\ntext:\n%s", i, err, buf.String())
+			continue
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("#%d:\ngot  %+v,\nwant %+v\n,\ntext:\n%s", i, out, in, buf.String())
+		}
+	}
+}
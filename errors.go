@@ -1,16 +1,86 @@
 package toml
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // ParseError describes errors raised in parsing phase.
 type ParseError struct {
-	Line int // 1-based
-	Pos  int // 0-based, relative to beginning of input
-	Err  error
+	Line   int    // 1-based
+	Column int    // 1-based, counted in runes
+	Pos    int    // 0-based, relative to beginning of input
+	Path   string // key path being parsed, empty if none
+	Source string // source line where error occurred
+	Err    error
 }
 
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("toml: line %d, pos %d: %s", e.Line, e.Pos, e.Err.Error())
+	if e.Path == "" {
+		return fmt.Sprintf("toml: line %d, column %d: %s", e.Line, e.Column, e.Err.Error())
+	}
+	return fmt.Sprintf("toml: line %d, column %d (%s): %s", e.Line, e.Column, e.Path, e.Err.Error())
+}
+
+// Excerpt renders source line where error occurred with a caret under
+// the error column, for example:
+//
+//	2 | port = 80a
+//	  |          ^
+func (e *ParseError) Excerpt() string {
+	line := strconv.Itoa(e.Line)
+	// Keep tabs in caret line, so it aligns with source line.
+	var caret []byte
+	for _, r := range e.Source {
+		if len(caret) >= e.Column-1 {
+			break
+		}
+		if r == '\t' {
+			caret = append(caret, '\t')
+		} else {
+			caret = append(caret, ' ')
+		}
+	}
+	for len(caret) < e.Column-1 {
+		caret = append(caret, ' ')
+	}
+	return fmt.Sprintf("%s | %s\n%s | %s^", line, e.Source, strings.Repeat(" ", len(line)), caret)
+}
+
+// ParseErrors describes all syntax errors found in parsing, in the order
+// they appear in input. It is reported only by ParseAll.
+type ParseErrors struct {
+	Errors []*ParseError
+}
+
+func (e *ParseErrors) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		return "toml: no errors"
+	case 1:
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0], len(e.Errors)-1)
+}
+
+// Is reports whether any syntax error matches target, see errors.Is.
+func (e *ParseErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds first syntax error that matches target, see errors.As.
+func (e *ParseErrors) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
 }
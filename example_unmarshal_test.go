@@ -2,6 +2,7 @@ package toml_test
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kezhuw/toml"
@@ -233,3 +234,25 @@ func ExampleUnmarshal_tagOmitempty() {
 	fmt.Println(out.Key)
 	// Output:
 }
+
+func ExampleDecoder() {
+	r := strings.NewReader(`
+	[server]
+	host = "localhost"
+	port = 8080
+	`)
+	var out struct {
+		Server struct {
+			Host string
+			Port int
+		}
+	}
+
+	err := toml.NewDecoder(r).Decode(&out)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%s:%d\n", out.Server.Host, out.Server.Port)
+	// Output: localhost:8080
+}
@@ -17,38 +17,87 @@ type Environment interface {
 type Array struct {
 	Closed bool
 	Elems  []Value
+	Ranges []Range // elements of static array
+}
+
+// Range is a range of bytes in source document, End is exclusive.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Field records where a key/value pair was defined in source document.
+type Field struct {
+	Key   Range
+	Value Range
 }
 
 type Table struct {
 	Implicit bool
+	Dotted   bool
+	Inline   bool
 	Elems    map[string]Value
+	Keys     []string
+	Lines    map[string]int
+	Ranges   map[string]Range // where keys were defined
+	Header   Range            // table header, zero if not defined by header
+	Fields   map[string]Field // key/value pairs defined in table
 }
 
 type String string
 
-type Integer int64
+// Integer is integer in range of int64, Text is its literal text.
+type Integer struct {
+	Value int64
+	Text  string
+}
+
+// BigInteger is literal text of integer out of range of int64.
+type BigInteger string
 
-type Float float64
+// Float is float, Text is its literal text. Value is ±Inf if Overflow,
+// that is float is out of range of float64.
+type Float struct {
+	Value    float64
+	Text     string
+	Overflow bool
+}
 
 type Boolean bool
 
 type Datetime time.Time
 
-func (t *Table) Type() string   { return "table" }
-func (a *Array) Type() string   { return "array" }
-func (s String) Type() string   { return "string" }
-func (i Integer) Type() string  { return "integer" }
-func (f Float) Type() string    { return "float" }
-func (b Boolean) Type() string  { return "boolean" }
-func (d Datetime) Type() string { return "datetime" }
-
-func (a *Array) TOMLValue()   {}
-func (t *Table) TOMLValue()   {}
-func (s String) TOMLValue()   {}
-func (i Integer) TOMLValue()  {}
-func (f Float) TOMLValue()    {}
-func (b Boolean) TOMLValue()  {}
-func (d Datetime) TOMLValue() {}
+type LocalDate time.Time
+
+type LocalTime time.Time
+
+type LocalDatetime time.Time
+
+func (t *Table) Type() string     { return "table" }
+func (a *Array) Type() string     { return "array" }
+func (s String) Type() string     { return "string" }
+func (i Integer) Type() string    { return "integer" }
+func (i BigInteger) Type() string { return "integer" }
+func (f Float) Type() string      { return "float" }
+func (b Boolean) Type() string    { return "boolean" }
+func (d Datetime) Type() string   { return "datetime" }
+
+func (d LocalDate) Type() string     { return "local date" }
+func (t LocalTime) Type() string     { return "local time" }
+func (d LocalDatetime) Type() string { return "local datetime" }
+
+func (a *Array) TOMLValue()     {}
+func (t *Table) TOMLValue()     {}
+func (s String) TOMLValue()     {}
+func (i Integer) TOMLValue()    {}
+func (i BigInteger) TOMLValue() {}
+func (f Float) TOMLValue()      {}
+func (b Boolean) TOMLValue()    {}
+func (d Datetime) TOMLValue()   {}
+
+func (d LocalDate) TOMLValue()     {}
+func (t LocalTime) TOMLValue()     {}
+func (d LocalDatetime) TOMLValue() {}
 
 func (a *Array) TomlEnvironment() {}
 func (t *Table) TomlEnvironment() {}
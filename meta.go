@@ -0,0 +1,149 @@
+package toml
+
+import (
+	"sort"
+
+	"github.com/kezhuw/toml/internal/types"
+)
+
+type metaKey struct {
+	path string
+	line int
+}
+
+type metaKeys []metaKey
+
+func (s metaKeys) Len() int      { return len(s) }
+func (s metaKeys) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s metaKeys) Less(i, j int) bool {
+	if s[i].line != s[j].line {
+		return s[i].line < s[j].line
+	}
+	return s[i].path < s[j].path
+}
+
+// MetaData describes keys defined in a TOML document, where they were
+// defined and whether they were decoded into Go values.
+//
+// Keys are identified by their paths, which are dotted keys with array
+// indices for tables in arrays, for example "servers.alpha.ip" and
+// "products[2].name". Keys with characters other than bare key characters
+// are quoted as basic strings.
+type MetaData struct {
+	keys    metaKeys
+	types   map[string]string
+	decoded map[string]struct{}
+
+	lines       *sourceLines
+	keyRanges   map[string]types.Range
+	valueRanges map[string]types.Range
+}
+
+func newMetaData(src string, t *types.Table, decoded map[string]struct{}) *MetaData {
+	md := &MetaData{
+		types:       make(map[string]string),
+		decoded:     decoded,
+		lines:       newSourceLines(src),
+		keyRanges:   make(map[string]types.Range),
+		valueRanges: make(map[string]types.Range),
+	}
+	md.walkTable("", t)
+	sort.Sort(md.keys)
+	return md
+}
+
+func (md *MetaData) walkValue(path string, v types.Value) {
+	md.types[path] = v.Type()
+	switch v := v.(type) {
+	case *types.Table:
+		md.walkTable(path, v)
+	case *types.Array:
+		for i, elem := range v.Elems {
+			elemPath := combineIndexPath(path, i)
+			if t, ok := elem.(*types.Table); ok && t.Header.End != 0 {
+				md.keyRanges[elemPath] = t.Header
+			}
+			if i < len(v.Ranges) {
+				md.valueRanges[elemPath] = v.Ranges[i]
+			}
+			md.walkValue(elemPath, elem)
+		}
+	}
+}
+
+func (md *MetaData) walkTable(path string, t *types.Table) {
+	for key, value := range t.Elems {
+		keyPath := combineKeyPath(path, key)
+		md.keys = append(md.keys, metaKey{keyPath, t.Lines[key]})
+		md.keyRanges[keyPath] = t.Ranges[key]
+		if f, ok := t.Fields[key]; ok {
+			md.valueRanges[keyPath] = f.Value
+		}
+		md.walkValue(keyPath, value)
+	}
+}
+
+// Keys returns paths of all keys defined in TOML document, in the order
+// they appear in the document.
+func (md *MetaData) Keys() []string {
+	keys := make([]string, len(md.keys))
+	for i, key := range md.keys {
+		keys[i] = key.path
+	}
+	return keys
+}
+
+// IsDefined reports whether the key path is defined in TOML document.
+// Array elements can be located using their indices.
+func (md *MetaData) IsDefined(path string) bool {
+	_, ok := md.types[path]
+	return ok
+}
+
+// Type returns TOML type name of the value at path, or empty string if
+// path is not defined in TOML document.
+func (md *MetaData) Type(path string) string {
+	return md.types[path]
+}
+
+// KeyPosition returns where key at path was defined, that is, the key of
+// key/value pair, or the table header which defines the table explicitly
+// or implicitly. Elements of arrays of tables are defined by their table
+// headers. It reports false if path is not defined or not defined by a
+// key, for example, elements of static arrays.
+func (md *MetaData) KeyPosition(path string) (Span, bool) {
+	r, ok := md.keyRanges[path]
+	if !ok {
+		return Span{}, false
+	}
+	return md.lines.span(r), true
+}
+
+// ValuePosition returns where value at path was written. It reports false
+// if path is not defined or value was not written in place, for example,
+// tables defined by table headers.
+func (md *MetaData) ValuePosition(path string) (Span, bool) {
+	r, ok := md.valueRanges[path]
+	if !ok {
+		return Span{}, false
+	}
+	return md.lines.span(r), true
+}
+
+// IsDecoded reports whether value at path was decoded into Go value.
+func (md *MetaData) IsDecoded(path string) bool {
+	_, ok := md.decoded[path]
+	return ok
+}
+
+// Undecoded returns paths of keys which are defined in TOML document but
+// not decoded into Go values, in the order they appear in the document.
+func (md *MetaData) Undecoded() []string {
+	var keys []string
+	for _, key := range md.keys {
+		if _, ok := md.decoded[key.path]; !ok {
+			keys = append(keys, key.path)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,157 @@
+package toml_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kezhuw/toml"
+)
+
+func TestUnmarshalMetaData(t *testing.T) {
+	in := `
+	name = "toml"
+	timeout = 0
+	deprecated = true
+	"quoted key" = 1
+	ports = [ 8080, 8081 ]
+	extra = { a = 1 }
+
+	[[servers]]
+	ip = "10.0.0.1"
+	dc = "eqdc10"
+
+	[[servers]]
+	ip = "10.0.0.2"
+	`
+	var out struct {
+		Name    string
+		Timeout int
+		Retries int
+		Ports   []int
+		Extra   interface{}
+		Servers []struct{ IP string }
+	}
+	md, err := toml.UnmarshalMetaData([]byte(in), &out)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	keys := []string{"name", "timeout", "deprecated", `"quoted key"`, "ports", "extra", "extra.a", "servers", "servers[0].ip", "servers[0].dc", "servers[1].ip"}
+	if got := md.Keys(); !reflect.DeepEqual(got, keys) {
+		t.Errorf("keys: got %q, want %q", got, keys)
+	}
+
+	undecoded := []string{"deprecated", `"quoted key"`, "servers[0].dc"}
+	if got := md.Undecoded(); !reflect.DeepEqual(got, undecoded) {
+		t.Errorf("undecoded: got %q, want %q", got, undecoded)
+	}
+
+	if !md.IsDefined("timeout") || md.IsDefined("retries") {
+		t.Errorf("timeout should be defined while retries should not")
+	}
+
+	if !md.IsDecoded("extra.a") || md.IsDecoded("deprecated") {
+		t.Errorf("extra.a should be decoded while deprecated should not")
+	}
+
+	types := map[string]string{
+		"name":       "string",
+		"timeout":    "integer",
+		"ports":      "array",
+		"ports[1]":   "integer",
+		"extra":      "table",
+		"servers":    "array",
+		"servers[1]": "table",
+		"retries":    "",
+	}
+	for path, typ := range types {
+		if got := md.Type(path); got != typ {
+			t.Errorf("type of %s: got %q, want %q", path, got, typ)
+		}
+	}
+}
+
+func TestDecoderDecodeMetaData(t *testing.T) {
+	dec := toml.NewDecoder(strings.NewReader("a = 1\nb = 2"))
+	dec.DisallowUnknownFields()
+	var out struct{ A int }
+	md, err := dec.DecodeMetaData(&out)
+	if _, ok := err.(*toml.UnknownFieldError); !ok {
+		t.Errorf("got error %v, want UnknownFieldError", err)
+	}
+	if got, want := md.Undecoded(), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("undecoded: got %q, want %q", got, want)
+	}
+
+	md, err = toml.NewDecoder(strings.NewReader("a = ")).DecodeMetaData(&out)
+	if err == nil || md != nil {
+		t.Errorf("got metadata %v and error %v, want nil metadata and parse error", md, err)
+	}
+}
+
+func TestMetaDataPosition(t *testing.T) {
+	in := "name = \"toml\"\n" +
+		"ports = [ 8080,\n  8081 ]\n" +
+		"\"ключ\" = { a = 1 }\n" +
+		"\n" +
+		"[servers.alpha]\n" +
+		"ip = \"10.0.0.1\"\n" +
+		"\n" +
+		"[[products]]\n" +
+		"[[products]]\n" +
+		"name = \"Nail\"\n"
+	var out interface{}
+	md, err := toml.UnmarshalMetaData([]byte(in), &out)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	pos := func(offset, line, column int) toml.Position {
+		return toml.Position{Offset: offset, Line: line, Column: column}
+	}
+	keys := map[string]toml.Span{
+		"name":             {pos(0, 1, 1), pos(4, 1, 5)},
+		"ports":            {pos(14, 2, 1), pos(19, 2, 6)},
+		`"ключ"`:           {pos(39, 4, 1), pos(49, 4, 7)},
+		`"ключ".a`:         {pos(54, 4, 12), pos(55, 4, 13)},
+		"servers":          {pos(63, 6, 1), pos(78, 6, 16)},
+		"servers.alpha":    {pos(63, 6, 1), pos(78, 6, 16)},
+		"servers.alpha.ip": {pos(79, 7, 1), pos(81, 7, 3)},
+		"products":         {pos(96, 9, 1), pos(108, 9, 13)},
+		"products[1]":      {pos(109, 10, 1), pos(121, 10, 13)},
+		"products[1].name": {pos(122, 11, 1), pos(126, 11, 5)},
+	}
+	for path, want := range keys {
+		got, ok := md.KeyPosition(path)
+		if !ok || got != want {
+			t.Errorf("key position of %s: got %v, %t, want %v", path, got, ok, want)
+		}
+	}
+
+	values := map[string]toml.Span{
+		"name":             {pos(7, 1, 8), pos(13, 1, 14)},
+		"ports":            {pos(22, 2, 9), pos(38, 3, 9)},
+		"ports[1]":         {pos(32, 3, 3), pos(36, 3, 7)},
+		`"ключ"`:           {pos(52, 4, 10), pos(61, 4, 19)},
+		`"ключ".a`:         {pos(58, 4, 16), pos(59, 4, 17)},
+		"servers.alpha.ip": {pos(84, 7, 6), pos(94, 7, 16)},
+	}
+	for path, want := range values {
+		got, ok := md.ValuePosition(path)
+		if !ok || got != want {
+			t.Errorf("value position of %s: got %v, %t, want %v", path, got, ok, want)
+		}
+	}
+
+	for _, path := range []string{"servers.alpha", "products", "products[0]", "missing"} {
+		if _, ok := md.ValuePosition(path); ok {
+			t.Errorf("value position of %s: got ok", path)
+		}
+	}
+	for _, path := range []string{"ports[0]", "missing"} {
+		if _, ok := md.KeyPosition(path); ok {
+			t.Errorf("key position of %s: got ok", path)
+		}
+	}
+}
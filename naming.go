@@ -0,0 +1,84 @@
+package toml
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A NamingStrategy converts name of Go struct field without tagged name to
+// TOML key. In encoding, it is applied to field names. In decoding, a TOML
+// key matches a field if they are converted to the same name, so custom
+// strategy should convert its outputs to themselves.
+type NamingStrategy func(name string) string
+
+// splitWords splits name into words at separators '_', '-' and spaces, and
+// at case changes, for example, "HTTPServerID" is split to "HTTP", "Server"
+// and "ID".
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			if !unicode.IsUpper(prev) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func joinLowerWords(name string, sep string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// SnakeCase converts name to snake case, for example, "MaxConns" to
+// "max_conns".
+func SnakeCase(name string) string {
+	return joinLowerWords(name, "_")
+}
+
+// KebabCase converts name to kebab case, for example, "MaxConns" to
+// "max-conns".
+func KebabCase(name string) string {
+	return joinLowerWords(name, "-")
+}
+
+// CamelCase converts name to lower camel case, for example, "MaxConns" to
+// "maxConns" and "ServerID" to "serverId".
+func CamelCase(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i != 0 {
+			r := []rune(word)
+			r[0] = unicode.ToUpper(r[0])
+			word = string(r)
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+// LowerCase converts name to lower case. As NamingStrategy, it matches
+// TOML keys to field names case-insensitively. Keys encoded by it are also
+// accepted in decoding without naming strategy, which tries lower case of
+// field names.
+func LowerCase(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,105 @@
+package toml_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kezhuw/toml"
+)
+
+var namingTests = []struct {
+	name  string
+	snake string
+	kebab string
+	camel string
+}{
+	{"MaxConns", "max_conns", "max-conns", "maxConns"},
+	{"max_conns", "max_conns", "max-conns", "maxConns"},
+	{"max-conns", "max_conns", "max-conns", "maxConns"},
+	{"maxConns", "max_conns", "max-conns", "maxConns"},
+	{"HTTPServerID", "http_server_id", "http-server-id", "httpServerId"},
+	{"V2Enabled", "v2_enabled", "v2-enabled", "v2Enabled"},
+	{"ID", "id", "id", "id"},
+}
+
+func TestNamingStrategy(t *testing.T) {
+	for _, test := range namingTests {
+		if got := toml.SnakeCase(test.name); got != test.snake {
+			t.Errorf("SnakeCase(%q): got %q, want %q", test.name, got, test.snake)
+		}
+		if got := toml.KebabCase(test.name); got != test.kebab {
+			t.Errorf("KebabCase(%q): got %q, want %q", test.name, got, test.kebab)
+		}
+		if got := toml.CamelCase(test.name); got != test.camel {
+			t.Errorf("CamelCase(%q): got %q, want %q", test.name, got, test.camel)
+		}
+	}
+}
+
+type namingConfig struct {
+	MaxConns  int
+	ServerID  string
+	Timeout   int `toml:"TimeOut"`
+	KeepAlive bool
+}
+
+var namingCodecTests = []struct {
+	naming toml.NamingStrategy
+	in     string
+	out    string
+}{
+	{
+		toml.SnakeCase,
+		"max_conns = 10\nserver_id = \"a\"\nTimeOut = 3\nKeep-Alive = true",
+		"max_conns = 10\nserver_id = \"a\"\nTimeOut = 3\nkeep_alive = true\n",
+	},
+	{
+		toml.KebabCase,
+		"max-conns = 10\nserver-id = \"a\"\nTimeOut = 3\nkeep-alive = true",
+		"max-conns = 10\nserver-id = \"a\"\nTimeOut = 3\nkeep-alive = true\n",
+	},
+	{
+		toml.CamelCase,
+		"maxConns = 10\nserverId = \"a\"\nTimeOut = 3\nkeep_alive = true",
+		"maxConns = 10\nserverId = \"a\"\nTimeOut = 3\nkeepAlive = true\n",
+	},
+	{
+		toml.LowerCase,
+		"MAXCONNS = 10\nserverid = \"a\"\nTimeOut = 3\nKeepAlive = true",
+		"maxconns = 10\nserverid = \"a\"\nTimeOut = 3\nkeepalive = true\n",
+	},
+	{
+		func(name string) string { return "x_" + strings.TrimPrefix(name, "x_") },
+		"x_MaxConns = 10\nx_ServerID = \"a\"\nTimeOut = 3\nKeepAlive = true",
+		"x_MaxConns = 10\nx_ServerID = \"a\"\nTimeOut = 3\nx_KeepAlive = true\n",
+	},
+}
+
+func TestNamingStrategyCodec(t *testing.T) {
+	want := namingConfig{MaxConns: 10, ServerID: "a", Timeout: 3, KeepAlive: true}
+	for i, test := range namingCodecTests {
+		dec := toml.NewDecoder(strings.NewReader(test.in))
+		dec.SetNamingStrategy(test.naming)
+		var out namingConfig
+		if err := dec.Decode(&out); err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("#%d: got %+v, want %+v", i, out, want)
+		}
+
+		var buf bytes.Buffer
+		enc := toml.NewEncoder(&buf)
+		enc.SetNamingStrategy(test.naming)
+		if err := enc.Encode(want); err != nil {
+			t.Errorf("#%d: got error: %s", i, err)
+			continue
+		}
+		if got := buf.String(); got != test.out {
+			t.Errorf("#%d: encode got:\n%s\nwant:\n%s", i, got, test.out)
+		}
+	}
+}
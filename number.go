@@ -0,0 +1,75 @@
+package toml
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// A Number represents TOML integer or float in its literal text, say,
+// "0x10", "1_000", "1e6" and "3.14159265358979323846264338327950288".
+//
+// Decoder.UseNumber causes the Decoder to store TOML numbers in interface
+// values as Number. Marshal encodes Number as TOML number in its text.
+type Number string
+
+// String returns text of the number.
+func (n Number) String() string { return string(n) }
+
+// Type returns "float" if n is written as TOML float, otherwise "integer".
+func (n Number) Type() string {
+	typ, _, _ := checkNumber(string(n))
+	return typ
+}
+
+func (n Number) tomlValue() {}
+
+// prefixBase returns base of n if it is written as hexadecimal, octal or
+// binary integer, otherwise 0.
+func (n Number) prefixBase() int {
+	if len(n) > 1 && n[0] == '0' {
+		switch n[1] {
+		case 'x':
+			return 16
+		case 'o':
+			return 8
+		case 'b':
+			return 2
+		}
+	}
+	return 0
+}
+
+// Float64 returns the number as float64.
+func (n Number) Float64() (float64, error) {
+	if n.prefixBase() == 0 {
+		return strconv.ParseFloat(string(n), 64)
+	}
+	// strconv.ParseFloat accepts no prefixed integers.
+	i, ok := new(big.Int).SetString(string(n), 0)
+	if !ok {
+		return 0, &strconv.NumError{Func: "ParseFloat", Num: string(n), Err: strconv.ErrSyntax}
+	}
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f, nil
+}
+
+// Int64 returns the number as int64. Decimal integers are in base 10 even
+// with leading zeros, say, "010" is 10.
+func (n Number) Int64() (int64, error) {
+	s, base := string(n), n.prefixBase()
+	if base == 0 {
+		base = 10
+	} else {
+		// Sign is not allowed after prefix.
+		s = s[2:]
+		if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+			return 0, &strconv.NumError{Func: "ParseInt", Num: string(n), Err: strconv.ErrSyntax}
+		}
+	}
+	i, err := strconv.ParseInt(strings.Replace(s, "_", "", -1), base, 64)
+	if err != nil {
+		err.(*strconv.NumError).Num = string(n)
+	}
+	return i, err
+}
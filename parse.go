@@ -2,6 +2,7 @@ package toml
 
 import (
 	"fmt"
+	"math"
 	"runtime"
 	"strconv"
 	"strings"
@@ -20,9 +21,12 @@ type scanner func(*parser) scanner
 type environment struct {
 	env  types.Environment
 	path string
+	key  string // key waiting for value in table environment
 }
 
 type parser struct {
+	version Version
+
 	mark int
 
 	pos     int
@@ -34,7 +38,10 @@ type parser struct {
 	envs []environment
 	keys []string
 
-	names []string // table name parsing
+	names []string // table name or dotted key parsing
+
+	fields []types.Field // key/value pairs and array elements being parsed
+	header int           // start of table header being parsed, -1 if none
 
 	str strParser
 	num numParser
@@ -42,9 +49,20 @@ type parser struct {
 	scanners []scanner
 
 	err error
+
+	recovery bool          // continue after syntax errors
+	errs     []*ParseError // syntax errors recovered from
+	defined  []definedKey  // keys defined by key/value pair of current line
+}
+
+// definedKey is a key defined in table by a top level key/value pair.
+type definedKey struct {
+	t   *types.Table
+	key string
 }
 
 type numParser struct {
+	base      int // 0 for decimal number, otherwise base of prefixed integer
 	sign      string
 	e         string
 	esign     string
@@ -54,6 +72,7 @@ type numParser struct {
 }
 
 func (p *numParser) reset() {
+	p.base = 0
 	p.sign = ""
 	p.e = ""
 	p.esign = ""
@@ -85,20 +104,115 @@ func (p *numParser) join(sep string) string {
 	return s
 }
 
-func (p *numParser) Float() (float64, error) {
+// Float returns types.Float, which overflows if float is out of range of
+// float64. Text is literal text of the float.
+func (p *numParser) Float(text string) (types.Value, error) {
 	defer p.reset()
+	if i := strings.Join(p.integers, ""); i != "0" && i[0] == '0' {
+		return nil, fmt.Errorf("leading zero in float %q", text)
+	}
 	s := p.join("")
-	return strconv.ParseFloat(s, 64)
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return types.Float{Value: f, Text: text}, nil
+	}
+	if err.(*strconv.NumError).Err != strconv.ErrRange {
+		return nil, err
+	}
+	return types.Float{Value: f, Text: text, Overflow: true}, nil
 }
 
-func (p *numParser) Integer() (int64, error) {
+// Integer returns types.Integer, or types.BigInteger if integer is out of
+// range of int64. Text is literal text of the integer.
+func (p *numParser) Integer(text string) (types.Value, error) {
 	defer p.reset()
 	s := strings.Join(p.integers, "")
-	if s != "0" && s[0] == '0' {
-		return 0, fmt.Errorf("leading zero in integer %q", s)
+	base := p.base
+	if base == 0 {
+		if s != "0" && s[0] == '0' {
+			return nil, fmt.Errorf("leading zero in integer %q", s)
+		}
+		base = 10
+		s = p.sign + s
+	}
+	i, err := strconv.ParseInt(s, base, 64)
+	if err == nil {
+		return types.Integer{Value: i, Text: text}, nil
+	}
+	if err.(*strconv.NumError).Err != strconv.ErrRange {
+		return nil, err
+	}
+	return types.BigInteger(text), nil
+}
+
+// digitsLen returns length of leading digits of base in s, digits may be
+// separated by single underscores.
+func digitsLen(s string, base int) int {
+	i := 0
+	for i < len(s) {
+		if s[i] == '_' && i != 0 && i+1 < len(s) && isBaseDigit(rune(s[i+1]), base) {
+			i++
+		} else if !isBaseDigit(rune(s[i]), base) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// checkNumber checks whether s is a valid TOML integer or float. It
+// returns type of s and the earliest version supporting it.
+func checkNumber(s string) (typ string, since Version, ok bool) {
+	t := s
+	signed := len(t) != 0 && (t[0] == '+' || t[0] == '-')
+	if signed {
+		t = t[1:]
+	}
+	switch t {
+	case "inf", "nan":
+		return "float", V1_0_0, true
+	}
+	if len(t) > 2 && t[0] == '0' {
+		base := 0
+		switch t[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 0 {
+			n := digitsLen(t[2:], base)
+			return "integer", V1_0_0, !signed && n != 0 && n == len(t)-2
+		}
+	}
+	typ, since = "integer", V0_4_0
+	i := digitsLen(t, 10)
+	if i == 0 || (t[0] == '0' && i != 1) {
+		return typ, since, false
+	}
+	if i < len(t) && t[i] == '.' {
+		typ = "float"
+		n := digitsLen(t[i+1:], 10)
+		if n == 0 {
+			return typ, since, false
+		}
+		i += 1 + n
 	}
-	s = p.sign + s
-	return strconv.ParseInt(s, 10, 64)
+	if i < len(t) && (t[i] == 'e' || t[i] == 'E') {
+		typ = "float"
+		i++
+		if i < len(t) && (t[i] == '+' || t[i] == '-') {
+			i++
+		}
+		n := digitsLen(t[i:], 10)
+		if n == 0 {
+			return typ, since, false
+		}
+		i += n
+	}
+	return typ, since, i == len(t)
 }
 
 type strParser struct {
@@ -120,6 +234,14 @@ func (s *strParser) join() string {
 	return strings.Join(s.parts, "")
 }
 
+func (p *parser) supports(v Version) bool {
+	return p.version >= v
+}
+
+func (p *parser) unsupported(feature string) scanner {
+	return p.errorScanner("%s is not supported in TOML %s", feature, p.version)
+}
+
 func (p *parser) pushScanner(s scanner) {
 	p.scanners = append(p.scanners, s)
 }
@@ -228,11 +350,38 @@ func (p *parser) clearBackups() {
 	p.backups = p.backups[:0]
 }
 
+// recordKey records current line and r as where key was defined in t.
+// Keys are also recorded in the order they were first defined.
+func (p *parser) recordKey(t *types.Table, key string, r types.Range) {
+	if t.Lines == nil {
+		t.Lines = make(map[string]int)
+		t.Ranges = make(map[string]types.Range)
+	}
+	if _, ok := t.Lines[key]; !ok {
+		t.Keys = append(t.Keys, key)
+	}
+	t.Lines[key] = p.line
+	t.Ranges[key] = r
+}
+
+// keyRange returns range of key in key/value pair being parsed.
+func (p *parser) keyRange() types.Range {
+	return p.fields[len(p.fields)-1].Key
+}
+
+// headerRange returns range of table header just parsed.
+func (p *parser) headerRange() types.Range {
+	return types.Range{Start: p.header, End: p.pos}
+}
+
 func (p *parser) pushTableKey(key string) scanner {
 	env, path := p.topEnv()
-	if value, ok := env.(*types.Table).Elems[key]; ok {
+	t := env.(*types.Table)
+	p.envs[len(p.envs)-1].key = key
+	if value, ok := t.Elems[key]; ok {
 		return p.errorScanner("table %s has key %s defined as %s", path, normalizeKey(key), value.Type())
 	}
+	p.recordKey(t, key, p.keyRange())
 	p.keys = append(p.keys, key)
 	return p.popScanner()
 }
@@ -251,22 +400,68 @@ func (p *parser) setValue(value types.Value) scanner {
 	env, path := p.topEnv()
 	switch env := env.(type) {
 	case *types.Array:
-		if len(env.Elems) != 0 {
+		if len(env.Elems) != 0 && !p.supports(V1_0_0) {
 			if first := env.Elems[0]; first.Type() != value.Type() {
 				return p.errorScanner("array %s expects element type %s, but got %s", path, first.Type(), value.Type())
 			}
 		}
 		env.Elems = append(env.Elems, value)
+		env.Ranges = append(env.Ranges, p.popField().Value)
 	case *types.Table:
 		key := p.popTableKey()
+		p.envs[len(p.envs)-1].key = ""
 		env.Elems[key] = value
+		if env.Fields == nil {
+			env.Fields = make(map[string]types.Field)
+		}
+		env.Fields[key] = p.popField()
 	}
 	return p.popScanner()
 }
 
+func (p *parser) pushField() {
+	p.fields = append(p.fields, types.Field{Key: types.Range{Start: p.pos}, Value: types.Range{Start: -1}})
+}
+
+// setKeyEnd records end of key in key/value pair being parsed, p.pos is
+// after '='.
+func (p *parser) setKeyEnd() {
+	f := &p.fields[len(p.fields)-1]
+	end := p.pos - 1
+	for end > f.Key.Start && isSpace(rune(p.input[end-1])) {
+		end--
+	}
+	f.Key.End = end
+}
+
+// setValueStart records start of value in key/value pair being parsed if
+// it was not recorded, or start of array element, p.pos is after the
+// first character of value.
+func (p *parser) setValueStart() {
+	env, _ := p.topEnv()
+	switch env.(type) {
+	case *types.Array:
+		p.fields = append(p.fields, types.Field{Value: types.Range{Start: p.pos - 1}})
+	case *types.Table:
+		if f := &p.fields[len(p.fields)-1]; f.Value.Start < 0 {
+			f.Value.Start = p.pos - 1
+		}
+	}
+}
+
+// popField pops key/value pair or array element being parsed, p.pos is
+// after its value.
+func (p *parser) popField() types.Field {
+	i := len(p.fields) - 1
+	f := p.fields[i]
+	p.fields = p.fields[:i]
+	f.Value.End = p.pos
+	return f
+}
+
 func (p *parser) resetEnv(env types.Environment, path string) {
 	p.envs = p.envs[:1]
-	p.envs[0] = environment{env, path}
+	p.envs[0] = environment{env, path, ""}
 }
 
 func (p *parser) pushEnv(new types.Environment) {
@@ -277,7 +472,7 @@ func (p *parser) pushEnv(new types.Environment) {
 	case *types.Array:
 		path = combineIndexPath(path, len(env.Elems))
 	}
-	p.envs = append(p.envs, environment{new, path})
+	p.envs = append(p.envs, environment{new, path, ""})
 }
 
 func (p *parser) popEnv() (env types.Environment, path string) {
@@ -374,6 +569,16 @@ func scanEscapedRune(p *parser) scanner {
 		return scanReturnString(p, "\"")
 	case '\\':
 		return scanReturnString(p, "\\")
+	case 'e':
+		if !p.supports(V1_1_0) {
+			return p.unsupported(`escape sequence \e`)
+		}
+		return scanReturnString(p, "\x1b")
+	case 'x':
+		if !p.supports(V1_1_0) {
+			return p.unsupported(`escape sequence \x`)
+		}
+		return scanUnicodeRune(p, 2)
 	case 'u':
 		return scanUnicodeRune(p, 4)
 	case 'U':
@@ -455,6 +660,8 @@ func scanArrayTableEnd(p *parser) scanner {
 	if env == nil {
 		return nil
 	}
+	env.Header = p.headerRange()
+	p.header = -1
 	p.resetEnv(env, path)
 	return scanTopEnd
 }
@@ -470,6 +677,8 @@ func scanTableEnd(p *parser) scanner {
 	if env == nil {
 		return nil
 	}
+	env.Header = p.headerRange()
+	p.header = -1
 
 	p.resetEnv(env, path)
 	return scanTopEnd
@@ -477,6 +686,7 @@ func scanTableEnd(p *parser) scanner {
 
 func scanTableStart(p *parser) scanner {
 	p.names = p.names[:0]
+	p.header = p.pos - 1
 	if p.tryReadByte('[') {
 		return p.seqScanner(scanTableNameStart, scanByte(']'), scanArrayTableEnd)
 	}
@@ -528,6 +738,43 @@ func (p *parser) appendTableName(name string) {
 	p.names = append(p.names, name)
 }
 
+func (p *parser) appendKeyName(name string) {
+	p.names = append(p.names, name)
+}
+
+// pushKeys locates table for scanned key in current environment, and
+// expects its value. Dotted keys create and define tables along the way.
+func (p *parser) pushKeys() scanner {
+	p.setKeyEnd()
+	i := len(p.names) - 1
+	top := len(p.envs) == 1
+	if i != 0 {
+		if !p.supports(V1_0_0) {
+			return p.unsupported("dotted key")
+		}
+		env, path := p.topEnv()
+		t := env.(*types.Table)
+		p.envs[len(p.envs)-1].key = p.names[0]
+		for _, name := range p.names[:i] {
+			if _, ok := t.Elems[name]; !ok && top {
+				p.defined = append(p.defined, definedKey{t, name})
+			}
+			t, path = p.locateDottedTable(t, path, name)
+		}
+		p.envs = append(p.envs, environment{t, path, ""})
+		p.pushScanner(scanDottedKeyEnd)
+	}
+	if top {
+		env, _ := p.topEnv()
+		t := env.(*types.Table)
+		if _, ok := t.Elems[p.names[i]]; !ok {
+			p.defined = append(p.defined, definedKey{t, p.names[i]})
+		}
+	}
+	p.pushScanner(scanValue)
+	return p.pushTableKey(p.names[i])
+}
+
 func scanTableNameInside(p *parser) scanner {
 	r := p.readByte()
 	switch {
@@ -574,6 +821,7 @@ func (p *parser) skipNewline(r rune) bool {
 }
 
 func scanTop(p *parser) scanner {
+	p.defined = p.defined[:0]
 	r := p.readByte()
 	switch {
 	case isSpace(r):
@@ -609,36 +857,75 @@ func scanTopEnd(p *parser) scanner {
 	}
 }
 
+func scanInlineTableEnd(p *parser) scanner {
+	t, _ := p.popEnv()
+	return p.setValue(t)
+}
+
 func scanInlineTableFieldEnd(p *parser) scanner {
 	r := p.readByte()
 	switch {
+	case p.skipNewline(r):
+		if !p.supports(V1_1_0) {
+			return p.unsupported("newline in inline table")
+		}
+		return scanInlineTableFieldEnd
+	case r == '#' && p.supports(V1_1_0):
+		return p.seqScanner(scanComment, scanInlineTableFieldEnd)
 	case isSpace(r):
 		return scanInlineTableFieldEnd
 	case r == ',':
-		return p.seqScanner(scanTableField, scanInlineTableFieldEnd)
+		return scanInlineTableFieldStart
 	case r == '}':
-		t, _ := p.popEnv()
-		return p.setValue(t)
+		return scanInlineTableEnd(p)
 	default:
 		return p.expectStr("inline table separator ',' or terminator '}'")
 	}
 }
 
+func scanInlineTableFieldStart(p *parser) scanner {
+	r := p.readByte()
+	switch {
+	case p.skipNewline(r):
+		if !p.supports(V1_1_0) {
+			return p.unsupported("newline in inline table")
+		}
+		return scanInlineTableFieldStart
+	case r == '#' && p.supports(V1_1_0):
+		return p.seqScanner(scanComment, scanInlineTableFieldStart)
+	case isSpace(r):
+		return scanInlineTableFieldStart
+	case r == '}':
+		if !p.supports(V1_1_0) {
+			return p.unsupported("trailing comma in inline table")
+		}
+		return scanInlineTableEnd(p)
+	default:
+		p.unread()
+		return p.seqScanner(scanTableField, scanInlineTableFieldEnd)
+	}
+}
+
 func scanInlineTableStart(p *parser) scanner {
 	r := p.readByte()
 	switch {
 	case p.skipNewline(r):
-		return p.errorScanner("newlines are not allowed in inline table")
+		if !p.supports(V1_1_0) {
+			return p.unsupported("newline in inline table")
+		}
+		return scanInlineTableStart
+	case r == '#' && p.supports(V1_1_0):
+		return p.seqScanner(scanComment, scanInlineTableStart)
 	case isSpace(r):
 		return scanInlineTableStart
 	case r == ',':
 		return p.errorScanner("unexpected ',' in inline table")
 	case r == '}':
-		t := &types.Table{Elems: make(map[string]types.Value)}
+		t := &types.Table{Inline: true, Elems: make(map[string]types.Value)}
 		return p.setValue(t)
 	default:
 		p.unread()
-		p.pushEnv(&types.Table{Elems: make(map[string]types.Value)})
+		p.pushEnv(&types.Table{Inline: true, Elems: make(map[string]types.Value)})
 		return p.seqScanner(scanTableField, scanInlineTableFieldEnd)
 	}
 }
@@ -703,7 +990,7 @@ func scanNumber(p *parser) scanner {
 	case r == 'e' || r == 'E':
 		p.num.e = string(r)
 		p.num.pushInteger(p.slice(-1))
-		return p.seqScanner(scanRecord0, scanDigit, scanFloatExponent)
+		return scanFloatExponentSign
 	default:
 		p.unread()
 		p.num.pushInteger(p.slice(0))
@@ -711,24 +998,66 @@ func scanNumber(p *parser) scanner {
 	}
 }
 
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	16: "hexadecimal",
+}
+
+func scanBaseDigit(p *parser) scanner {
+	r := p.readByte()
+	if !isBaseDigit(r, p.num.base) {
+		return p.expectStr(baseNames[p.num.base] + " digit")
+	}
+	return p.popScanner()
+}
+
+func scanBaseInteger(p *parser) scanner {
+	r := p.readByte()
+	switch {
+	case isBaseDigit(r, p.num.base):
+		return scanBaseInteger
+	case r == '_':
+		p.num.pushInteger(p.slice(-1))
+		return p.seqScanner(scanRecord0, scanBaseDigit, scanBaseInteger)
+	default:
+		p.unread()
+		p.num.pushInteger(p.slice(0))
+		return setIntegerValue(p)
+	}
+}
+
+func scanBaseIntegerStart(p *parser, base int) scanner {
+	if !p.supports(V1_0_0) {
+		return p.unsupported(baseNames[base] + " integer")
+	}
+	p.num.base = base
+	return p.seqScanner(scanRecord0, scanBaseDigit, scanBaseInteger)
+}
+
 func scanNumberStart(p *parser) scanner {
 	return p.seqScanner(scanRecord0, scanDigit, scanNumber)
 }
 
+// valueText returns text of value being parsed, p.pos is after the value.
+func (p *parser) valueText() string {
+	return p.input[p.fields[len(p.fields)-1].Value.Start:p.pos]
+}
+
 func setFloatValue(p *parser) scanner {
-	f, err := p.num.Float()
+	f, err := p.num.Float(p.valueText())
 	if err != nil {
 		return p.setError(err)
 	}
-	return p.setValue(types.Float(f))
+	return p.setValue(f)
 }
 
 func setIntegerValue(p *parser) scanner {
-	i, err := p.num.Integer()
+	i, err := p.num.Integer(p.valueText())
 	if err != nil {
 		return p.setError(err)
 	}
-	return p.setValue(types.Integer(i))
+	return p.setValue(i)
 }
 
 func setStringValue(p *parser) scanner {
@@ -778,7 +1107,7 @@ func scanArrayStart(p *parser) scanner {
 func scanArrayEnd(p *parser) scanner {
 	r := p.readByte()
 	switch {
-	case isSpace(r):
+	case isSpace(r) || p.skipNewline(r):
 		return scanArrayEnd
 	case r == '#':
 		return p.seqScanner(scanComment, scanArrayEnd)
@@ -794,6 +1123,9 @@ func scanArrayEnd(p *parser) scanner {
 
 func scanValue(p *parser) scanner {
 	r := p.readByte()
+	if !isSpace(r) {
+		p.setValueStart()
+	}
 	switch {
 	case r == '[':
 		return scanArrayStart
@@ -809,13 +1141,48 @@ func scanValue(p *parser) scanner {
 			return p.expectStr("false")
 		}
 		return p.setValue(types.Boolean(false))
+	case r == 'i':
+		if !p.tryReadPrefix("nf") {
+			return p.expectStr("inf")
+		}
+		if !p.supports(V1_0_0) {
+			return p.unsupported("float inf")
+		}
+		return p.setValue(types.Float{Value: math.Inf(1), Text: p.valueText()})
+	case r == 'n':
+		if !p.tryReadPrefix("an") {
+			return p.expectStr("nan")
+		}
+		if !p.supports(V1_0_0) {
+			return p.unsupported("float nan")
+		}
+		return p.setValue(types.Float{Value: math.NaN(), Text: p.valueText()})
 	case r == '"':
 		return scanStringStart
 	case r == '\'':
 		return scanLiteralStart
 	case r == '+' || r == '-':
+		if s := p.input[p.pos:]; strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") || strings.HasPrefix(s, "0b") {
+			return p.errorScanner("sign is not allowed in prefixed integer")
+		}
+		switch {
+		case !p.supports(V1_0_0):
+		case p.tryReadPrefix("inf"):
+			if r == '-' {
+				return p.setValue(types.Float{Value: math.Inf(-1), Text: p.valueText()})
+			}
+			return p.setValue(types.Float{Value: math.Inf(1), Text: p.valueText()})
+		case p.tryReadPrefix("nan"):
+			return p.setValue(types.Float{Value: math.NaN(), Text: p.valueText()})
+		}
 		p.num.sign = string(r)
 		return scanNumberStart
+	case r == '0' && p.tryReadByte('x'):
+		return scanBaseIntegerStart(p, 16)
+	case r == '0' && p.tryReadByte('o'):
+		return scanBaseIntegerStart(p, 8)
+	case r == '0' && p.tryReadByte('b'):
+		return scanBaseIntegerStart(p, 2)
 	case isDigit(r):
 		p.record(-1)
 		return scanNumberOrDate
@@ -826,54 +1193,95 @@ func scanValue(p *parser) scanner {
 	}
 }
 
-func scanDateValue(p *parser, suffix string) scanner {
-	s := p.slice(0) + suffix
-	t, err := time.Parse(time.RFC3339Nano, s)
+// insertSeconds inserts omitted seconds to time in s starting at i.
+func insertSeconds(s string, i int) string {
+	if len(s) > i+5 && s[i+5] == ':' {
+		return s
+	}
+	return s[:i+5] + ":00" + s[i+5:]
+}
+
+func scanTimeValue(p *parser, layout string, value func(t time.Time) types.Value) scanner {
+	s := strings.ToUpper(p.slice(0))
+	switch {
+	case layout == localTimeLayout:
+		s = insertSeconds(s, 0)
+	case len(s) > 10:
+		s = s[:10] + "T" + insertSeconds(s[11:], 0)
+	}
+	t, err := time.Parse(layout, s)
 	if err != nil {
-		return p.errorScanner(err.Error())
+		return p.setError(err)
 	}
-	return p.setValue(types.Datetime(t))
+	return p.setValue(value(t))
 }
 
-func scanDateEnd(p *parser) scanner {
-	return scanDateValue(p, "")
+func setDatetimeValue(p *parser) scanner {
+	return scanTimeValue(p, time.RFC3339Nano, func(t time.Time) types.Value { return types.Datetime(t) })
+}
+
+func setLocalDatetimeValue(p *parser) scanner {
+	if !p.supports(V1_0_0) {
+		return p.unsupported("local datetime")
+	}
+	return scanTimeValue(p, localDateTimeLayout, func(t time.Time) types.Value { return types.LocalDatetime(t) })
+}
+
+func setLocalDateValue(p *parser) scanner {
+	if !p.supports(V1_0_0) {
+		return p.unsupported("local date")
+	}
+	return scanTimeValue(p, localDateLayout, func(t time.Time) types.Value { return types.LocalDate(t) })
+}
+
+func setLocalTimeValue(p *parser) scanner {
+	if !p.supports(V1_0_0) {
+		return p.unsupported("local time")
+	}
+	return scanTimeValue(p, localTimeLayout, func(t time.Time) types.Value { return types.LocalTime(t) })
 }
 
 func scanDateTimeEnd(p *parser) scanner {
 	r := p.readByte()
 	switch r {
-	case 'Z':
-		return scanDateEnd(p)
-	case '-':
-		return p.seqScanner(scanDigit, scanDigit, scanColon, scanDigit, scanDigit, scanDateEnd)
+	case 'Z', 'z':
+		return setDatetimeValue(p)
+	case '+', '-':
+		return p.seqScanner(scanDigit, scanDigit, scanColon, scanDigit, scanDigit, setDatetimeValue)
 	default:
 		p.unread()
-		return scanDateValue(p, "Z")
+		return setLocalDatetimeValue(p)
 	}
 }
 
-func scanDateTimeFraction(p *parser) scanner {
-	r := p.readByte()
-	switch r {
-	case '.':
-		for isDigit(p.readByte()) {
-		}
-		fallthrough
-	default:
-		p.unread()
-		return scanDateTimeEnd
+func scanTimeFraction(p *parser) scanner {
+	if p.tryReadByte('.') {
+		return p.seqScanner(scanDigit, scanConsumeByte(isDigit))
+	}
+	return p.popScanner()
+}
+
+// scanTimeSecond scans seconds of time, which are optional since TOML v1.1.0.
+func scanTimeSecond(p *parser) scanner {
+	if p.peekByte() != ':' && p.supports(V1_1_0) {
+		return p.popScanner()
 	}
+	return p.seqScanner(scanColon, scanDigit, scanDigit, scanTimeFraction)
 }
 
 func scanDateTime(p *parser) scanner {
 	r := p.readByte()
-	switch r {
-	case 'T':
-		return p.seqScanner(scanDigit, scanDigit, scanColon, scanDigit, scanDigit, scanColon, scanDigit, scanDigit, scanDateTimeFraction)
+	switch {
+	case r == 'T' || r == 't':
+	case r == ' ' && isDigit(p.peekByte()):
+		if !p.supports(V1_0_0) {
+			return p.unsupported("space delimited datetime")
+		}
 	default:
 		p.unread()
-		return scanDateValue(p, "T00:00:00Z")
+		return setLocalDateValue(p)
 	}
+	return p.seqScanner(scanDigit, scanDigit, scanColon, scanDigit, scanDigit, scanTimeSecond, scanDateTimeEnd)
 }
 
 func scanNumberOrDate(p *parser) scanner {
@@ -881,6 +1289,8 @@ func scanNumberOrDate(p *parser) scanner {
 	switch {
 	case r == '-':
 		return p.seqScanner(scanDigit, scanDigit, scanHash, scanDigit, scanDigit, scanDateTime)
+	case r == ':' && p.pos-p.mark == 3:
+		return p.seqScanner(scanDigit, scanDigit, scanTimeSecond, setLocalTimeValue)
 	case isDigit(r):
 		return scanNumberOrDate
 	default:
@@ -889,15 +1299,22 @@ func scanNumberOrDate(p *parser) scanner {
 	}
 }
 
-func scanFieldAssign(p *parser) scanner {
+func scanKeyStart(p *parser) scanner {
 	r := p.readByte()
 	switch {
 	case isSpace(r):
-		return scanFieldAssign
-	case r == '=':
-		return scanValue
+		return scanKeyStart
+	case isBareKeyChar(r):
+		p.record(-1)
+		return scanBareKey
+	case r == '=' || r == '.':
+		return p.errorScanner("key must be non-empty")
+	case r == '"':
+		return p.seqScanner(scanRecord0, scanString, scanKeyString)
+	case r == '\'':
+		return p.seqScanner(scanRecord0, scanLiteral, scanKeyString)
 	default:
-		return p.expectRune('=')
+		return p.expectStr("table field")
 	}
 }
 
@@ -907,43 +1324,51 @@ func scanBareKey(p *parser) scanner {
 	case isBareKeyChar(r):
 		return scanBareKey
 	case isSpace(r):
-		key := p.slice(-1)
-		p.pushScanner(scanFieldAssign)
-		return p.pushTableKey(key)
+		p.appendKeyName(p.slice(-1))
+		return scanKeyEnd
+	case r == '.':
+		p.appendKeyName(p.slice(-1))
+		return scanKeyStart
 	case r == '=':
-		key := p.slice(-1)
-		p.pushScanner(scanValue)
-		return p.pushTableKey(key)
+		p.appendKeyName(p.slice(-1))
+		return p.pushKeys()
 	default:
 		return p.expectStr("bare character")
 	}
 }
 
-func scanKeyEnd(p *parser) scanner {
-	key := p.str.join()
-	p.pushScanner(scanFieldAssign)
-	return p.pushTableKey(key)
+func scanKeyString(p *parser) scanner {
+	p.appendKeyName(p.str.join())
+	return scanKeyEnd
 }
 
-func scanTableField(p *parser) scanner {
+func scanKeyEnd(p *parser) scanner {
 	r := p.readByte()
 	switch {
 	case isSpace(r):
-		return scanTableField
-	case isBareKeyChar(r):
-		p.record(-1)
-		return scanBareKey
+		return scanKeyEnd
+	case r == '.':
+		return scanKeyStart
 	case r == '=':
-		return p.errorScanner("key must be non-empty")
-	case r == '"':
-		return p.seqScanner(scanRecord0, scanString, scanKeyEnd)
-	case r == '\'':
-		return p.seqScanner(scanRecord0, scanLiteral, scanKeyEnd)
+		return p.pushKeys()
 	default:
-		return p.expectStr("table field")
+		return p.expectRune('=')
 	}
 }
 
+// scanDottedKeyEnd restores environment after value of dotted key was set.
+func scanDottedKeyEnd(p *parser) scanner {
+	p.popEnv()
+	p.envs[len(p.envs)-1].key = ""
+	return p.popScanner()
+}
+
+func scanTableField(p *parser) scanner {
+	p.names = p.names[:0]
+	p.pushField()
+	return scanKeyStart
+}
+
 type char rune
 
 func (c char) String() string {
@@ -956,19 +1381,54 @@ func (c char) String() string {
 func (p *parser) expectRune(r rune) scanner {
 	p.unread()
 	got, _ := p.peekRune()
-	p.err = &ParseError{p.line, p.pos, fmt.Errorf("expect %q, got %s", r, char(got))}
+	p.err = p.newError(fmt.Errorf("expect %q, got %s", r, char(got)))
 	return nil
 }
 
 func (p *parser) expectStr(str string) scanner {
 	p.unread()
 	got, _ := p.peekRune()
-	p.err = &ParseError{p.line, p.pos, fmt.Errorf("expect %s, got %s", str, char(got))}
+	p.err = p.newError(fmt.Errorf("expect %s, got %s", str, char(got)))
 	return nil
 }
 
+// path returns key path being parsed.
+func (p *parser) path() string {
+	if p.header >= 0 {
+		return joinKeys(p.names)
+	}
+	env := p.envs[len(p.envs)-1]
+	switch v := env.env.(type) {
+	case *types.Array:
+		return combineIndexPath(env.path, len(v.Elems))
+	default:
+		if env.key == "" {
+			return env.path
+		}
+		return combineKeyPath(env.path, env.key)
+	}
+}
+
+func (p *parser) newError(err error) *ParseError {
+	start := strings.LastIndexAny(p.input[:p.pos], "\r\n") + 1
+	end := strings.IndexAny(p.input[p.pos:], "\r\n")
+	if end < 0 {
+		end = len(p.input)
+	} else {
+		end += p.pos
+	}
+	return &ParseError{
+		Line:   p.line,
+		Column: utf8.RuneCountInString(p.input[start:p.pos]) + 1,
+		Pos:    p.pos,
+		Path:   p.path(),
+		Source: p.input[start:end],
+		Err:    err,
+	}
+}
+
 func (p *parser) errorf(format string, args ...interface{}) error {
-	return &ParseError{p.line, p.pos, fmt.Errorf(format, args...)}
+	return p.newError(fmt.Errorf(format, args...))
 }
 
 func (p *parser) errorScanner(format string, args ...interface{}) scanner {
@@ -977,7 +1437,7 @@ func (p *parser) errorScanner(format string, args ...interface{}) scanner {
 }
 
 func (p *parser) setError(err error) scanner {
-	p.err = &ParseError{p.line, p.pos, err}
+	p.err = p.newError(err)
 	return nil
 }
 
@@ -989,8 +1449,12 @@ func (p *parser) locateTable(names []string) (t *types.Table, path string) {
 		case nil:
 			ti := &types.Table{Implicit: true, Elems: make(map[string]types.Value)}
 			t.Elems[name] = ti
+			p.recordKey(t, name, p.headerRange())
 			t = ti
 		case *types.Table:
+			if v.Inline {
+				panic(p.errorf("inline table %s can't be extended by table header", path))
+			}
 			t = v
 		case *types.Array:
 			if v.Closed {
@@ -1012,12 +1476,14 @@ func (p *parser) createTable(env *types.Table, path string, name string) (*types
 	case nil:
 		t := &types.Table{Elems: make(map[string]types.Value)}
 		env.Elems[name] = t
+		p.recordKey(env, name, p.headerRange())
 		return t, path
 	case *types.Table:
 		if !v.Implicit {
 			panic(p.errorf("table %s was defined twice", path))
 		}
 		v.Implicit = false
+		p.recordKey(env, name, p.headerRange())
 		return v, path
 	default:
 		panic(p.errorf("%s was defined as %s", path, v.Type()))
@@ -1030,6 +1496,7 @@ func (p *parser) createTableArray(env *types.Table, path string, name string) (*
 	switch v := env.Elems[name].(type) {
 	case nil:
 		env.Elems[name] = &types.Array{Elems: []types.Value{t}}
+		p.recordKey(env, name, p.headerRange())
 	case *types.Array:
 		if v.Closed {
 			panic(p.errorf("%s was defined as array", path))
@@ -1041,6 +1508,24 @@ func (p *parser) createTableArray(env *types.Table, path string, name string) (*
 	return t, path
 }
 
+func (p *parser) locateDottedTable(env *types.Table, path string, name string) (*types.Table, string) {
+	path = combineKeyPath(path, name)
+	switch v := env.Elems[name].(type) {
+	case nil:
+		t := &types.Table{Dotted: true, Elems: make(map[string]types.Value)}
+		env.Elems[name] = t
+		p.recordKey(env, name, p.keyRange())
+		return t, path
+	case *types.Table:
+		if !v.Dotted {
+			panic(p.errorf("table %s was defined, can't be extended by dotted keys", path))
+		}
+		return v, path
+	default:
+		panic(p.errorf("%s was defined as %s", path, v.Type()))
+	}
+}
+
 func (p *parser) errRecover(errp *error) {
 	if r := recover(); r != nil {
 		switch err := r.(type) {
@@ -1051,12 +1536,33 @@ func (p *parser) errRecover(errp *error) {
 		case *ParseError:
 			*errp = err
 		case error:
-			*errp = &ParseError{p.line, p.pos, err}
+			*errp = p.newError(err)
 		}
 	}
 }
 
-func (p *parser) parse() (err error) {
+func (p *parser) parse() error {
+	for {
+		err := p.scan()
+		if err == nil {
+			break
+		}
+		if !p.recovery {
+			return err
+		}
+		p.errs = append(p.errs, err.(*ParseError))
+		p.undefine()
+		if !p.resync() {
+			break
+		}
+	}
+	if len(p.errs) != 0 {
+		return &ParseErrors{Errors: p.errs}
+	}
+	return nil
+}
+
+func (p *parser) scan() (err error) {
 	defer p.errRecover(&err)
 	scanner := scanTop
 	for scanner != nil {
@@ -1066,23 +1572,79 @@ func (p *parser) parse() (err error) {
 	return p.err
 }
 
-func newParser(t *types.Table, s string) *parser {
+// undefine removes keys defined by key/value pair where error occurred,
+// so partial table contains no values from malformed lines.
+func (p *parser) undefine() {
+	for i := len(p.defined) - 1; i >= 0; i-- {
+		t, key := p.defined[i].t, p.defined[i].key
+		delete(t.Elems, key)
+		delete(t.Lines, key)
+		delete(t.Ranges, key)
+		delete(t.Fields, key)
+		for j, k := range t.Keys {
+			if k == key {
+				t.Keys = append(t.Keys[:j], t.Keys[j+1:]...)
+				break
+			}
+		}
+	}
+	p.defined = p.defined[:0]
+}
+
+// resync skips rest of line where error occurred, and resets parser to
+// scan next line in current table. It reports false if there is no line
+// left.
+func (p *parser) resync() bool {
+	i := strings.IndexByte(p.input[p.pos:], '\n')
+	if i < 0 {
+		return false
+	}
+	p.pos += i + 1
+	p.line = strings.Count(p.input[:p.pos], "\n") + 1
+	p.mark = -1
+	p.err = nil
+	p.clearBackups()
+	p.envs = p.envs[:1]
+	p.envs[0].key = ""
+	p.keys = p.keys[:0]
+	p.names = p.names[:0]
+	p.fields = p.fields[:0]
+	p.header = -1
+	p.str.reset()
+	p.num.reset()
+	p.scanners = p.scanners[:0]
+	return true
+}
+
+func newParser(t *types.Table, s string, version Version) *parser {
 	return &parser{
-		mark:  -1,
-		line:  1,
-		input: s,
-		root:  t,
-		envs:  []environment{{t, ""}},
+		version: version.normalize(),
+		mark:    -1,
+		line:    1,
+		input:   s,
+		root:    t,
+		envs:    []environment{{t, "", ""}},
+		header:  -1,
 	}
 }
 
-// Parse parses TOML document from data, and represents it in types.Table.
-func parse(data []byte) (*types.Table, error) {
+// Parse parses TOML document from data in given version of TOML
+// specification, and represents it in types.Table.
+func parse(data []byte, version Version) (*types.Table, error) {
 	root := &types.Table{Elems: make(map[string]types.Value)}
-	p := newParser(root, string(data))
+	p := newParser(root, string(data), version)
 	err := p.parse()
 	if err != nil {
 		return nil, err
 	}
 	return root, nil
 }
+
+// parseAll is like parse, but continues parsing after syntax errors and
+// returns partial table along with *ParseErrors.
+func parseAll(data []byte, version Version) (*types.Table, error) {
+	root := &types.Table{Elems: make(map[string]types.Value)}
+	p := newParser(root, string(data), version)
+	p.recovery = true
+	return root, p.parse()
+}
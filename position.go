@@ -0,0 +1,53 @@
+package toml
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/kezhuw/toml/internal/types"
+)
+
+// Position describes a position in TOML document.
+type Position struct {
+	Offset int // 0-based, relative to beginning of input
+	Line   int // 1-based
+	Column int // 1-based, counted in runes
+}
+
+// Span describes a range in TOML document, End is exclusive.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// sourceLines locates positions of offsets in source.
+type sourceLines struct {
+	src    string
+	starts []int // starts of lines
+}
+
+func newSourceLines(src string) *sourceLines {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\r':
+			if i+1 < len(src) && src[i+1] == '\n' {
+				continue
+			}
+			fallthrough
+		case '\n':
+			starts = append(starts, i+1)
+		}
+	}
+	return &sourceLines{src: src, starts: starts}
+}
+
+func (s *sourceLines) position(offset int) Position {
+	i := sort.SearchInts(s.starts, offset+1) - 1
+	start := s.starts[i]
+	return Position{Offset: offset, Line: i + 1, Column: utf8.RuneCountInString(s.src[start:offset]) + 1}
+}
+
+func (s *sourceLines) span(r types.Range) Span {
+	return Span{Start: s.position(r.Start), End: s.position(r.End)}
+}
@@ -8,13 +8,25 @@ func isHex(r rune) bool {
 	switch {
 	default:
 		return false
-	case 'A' <= r && r <= 'Z':
-	case 'a' <= r && r <= 'z':
+	case 'A' <= r && r <= 'F':
+	case 'a' <= r && r <= 'f':
 	case '0' <= r && r <= '9':
 	}
 	return true
 }
 
+func isBaseDigit(r rune, base int) bool {
+	switch base {
+	case 2:
+		return r == '0' || r == '1'
+	case 8:
+		return '0' <= r && r <= '7'
+	case 16:
+		return isHex(r)
+	}
+	return isDigit(r)
+}
+
 func isSpace(r rune) bool {
 	return r == ' ' || r == '\t'
 }
@@ -0,0 +1,195 @@
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathError describes an invalid key path.
+type PathError struct {
+	Path   string
+	Offset int // 0-based, relative to beginning of path
+	Msg    string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("toml: key path %q, offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+type pathStep struct {
+	key      string
+	index    int // -1 for key step
+	wildcard bool
+	offset   int
+}
+
+type pathParser struct {
+	path string
+	pos  int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return &PathError{Path: p.path, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *pathParser) parseKey() (pathStep, error) {
+	s := p.path[p.pos:]
+	switch {
+	case s == "":
+		return pathStep{}, p.errorf("expect key")
+	case s[0] == '*':
+		p.pos++
+		return pathStep{index: -1, wildcard: true}, nil
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return pathStep{}, p.errorf("unterminated literal string")
+		}
+		p.pos += end + 2
+		return pathStep{key: s[1 : end+1], index: -1}, nil
+	case s[0] == '"':
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return pathStep{}, p.errorf("unterminated basic string")
+		}
+		key, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return pathStep{}, p.errorf("invalid basic string %s", s[:end+1])
+		}
+		p.pos += end + 1
+		return pathStep{key: key, index: -1}, nil
+	}
+	n := 0
+	for n < len(s) && s[n] < 0x80 && isBareKeyChar(rune(s[n])) {
+		n++
+	}
+	if n == 0 {
+		return pathStep{}, p.errorf("unexpected %q", s[0])
+	}
+	p.pos += n
+	return pathStep{key: s[:n], index: -1}, nil
+}
+
+func (p *pathParser) parseIndex() (pathStep, error) {
+	s := p.path[p.pos:]
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathStep{}, p.errorf("unterminated index")
+	}
+	if s[:end] == "*" {
+		p.pos += end + 1
+		return pathStep{wildcard: true}, nil
+	}
+	i, err := strconv.Atoi(s[:end])
+	if err != nil || i < 0 || s[0] == '+' {
+		return pathStep{}, p.errorf("invalid index %q", s[:end])
+	}
+	p.pos += end + 1
+	return pathStep{index: i}, nil
+}
+
+// parsePath parses key path in form of what combineKeyPath and
+// combineIndexPath produce, with "*" as wildcard for keys and indices.
+func parsePath(path string) ([]pathStep, error) {
+	p := &pathParser{path: path}
+	step, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	steps := []pathStep{step}
+	for p.pos < len(path) {
+		offset := p.pos
+		switch path[p.pos] {
+		case '.':
+			p.pos++
+			offset = p.pos
+			step, err = p.parseKey()
+		case '[':
+			p.pos++
+			step, err = p.parseIndex()
+		default:
+			err = p.errorf("unexpected %q", path[p.pos])
+		}
+		if err != nil {
+			return nil, err
+		}
+		step.offset = offset
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func queryValues(v Value, steps []pathStep, results []Value) []Value {
+	if len(steps) == 0 {
+		return append(results, v)
+	}
+	step, steps := steps[0], steps[1:]
+	switch v := v.(type) {
+	case *Table:
+		if step.index >= 0 {
+			break
+		}
+		if !step.wildcard {
+			if elem := v.elems[step.key]; elem != nil {
+				results = queryValues(elem, steps, results)
+			}
+			break
+		}
+		for _, key := range v.keys {
+			results = queryValues(v.elems[key], steps, results)
+		}
+	case *Array:
+		switch {
+		case step.wildcard && step.index >= 0:
+			for _, elem := range v.elems {
+				results = queryValues(elem, steps, results)
+			}
+		case step.index >= 0 && step.index < len(v.elems):
+			results = queryValues(v.elems[step.index], steps, results)
+		}
+	}
+	return results
+}
+
+// Query returns value at key path in t, or nil if there is no such value.
+//
+// Key path consists of keys separated by dots, with array indices in
+// brackets, for example "servers.alpha.ip" and "products[2].name". Keys
+// could be bare keys, basic strings or literal strings, for example
+// `site."google.com"` and `site.'google.com'`.
+//
+// Wildcards are not allowed in path, use QueryAll instead.
+func (t *Table) Query(path string) (Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if step.wildcard {
+			return nil, &PathError{Path: path, Offset: step.offset, Msg: "wildcard is not allowed"}
+		}
+	}
+	if values := queryValues(t, steps, nil); len(values) != 0 {
+		return values[0], nil
+	}
+	return nil, nil
+}
+
+// QueryAll returns all values matching key path in t, in document order.
+// Besides what Query accepts, "*" matches all keys in a table, and "[*]"
+// matches all elements in an array, for example "servers.*.ip" and
+// "products[*].name".
+func (t *Table) QueryAll(path string) ([]Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return queryValues(t, steps, nil), nil
+}
@@ -0,0 +1,130 @@
+package toml_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kezhuw/toml"
+)
+
+const queryDocument = `
+title = "example"
+"site.name" = "quoted"
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[servers.beta]
+ip = "10.0.0.2"
+
+[servers.gamma]
+dc = "eqdc10"
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+
+[[products]]
+name = "Screw"
+tags = [ "small", "metal" ]
+`
+
+var queryTests = []struct {
+	path string
+	want toml.Value
+}{
+	{"title", toml.String("example")},
+	{`"site.name"`, toml.String("quoted")},
+	{`'site.name'`, toml.String("quoted")},
+	{"servers.alpha.ip", toml.String("10.0.0.1")},
+	{`servers."beta".ip`, toml.String("10.0.0.2")},
+	{"products[2].name", toml.String("Screw")},
+	{"products[2].tags[1]", toml.String("metal")},
+	{"products[3].name", nil},
+	{"servers.gamma.ip", nil},
+	{"title.name", nil},
+	{"title[0]", nil},
+	{"products.name", nil},
+}
+
+func TestQuery(t *testing.T) {
+	root, err := toml.Parse([]byte(queryDocument))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, test := range queryTests {
+		got, err := root.Query(test.path)
+		if err != nil {
+			t.Errorf("%s: got error: %s", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.path, got, test.want)
+		}
+	}
+}
+
+var queryAllTests = []struct {
+	path string
+	want []toml.Value
+}{
+	{"servers.*.ip", []toml.Value{toml.String("10.0.0.1"), toml.String("10.0.0.2")}},
+	{"products[*].name", []toml.Value{toml.String("Hammer"), toml.String("Nail"), toml.String("Screw")}},
+	{"products[*].tags[*]", []toml.Value{toml.String("small"), toml.String("metal")}},
+	{"servers.*.*", []toml.Value{toml.String("10.0.0.1"), toml.String("10.0.0.2"), toml.String("eqdc10")}},
+	{"products.*", nil},
+	{"servers[*]", nil},
+	{"missing.*", nil},
+}
+
+func TestQueryAll(t *testing.T) {
+	root, err := toml.Parse([]byte(queryDocument))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, test := range queryAllTests {
+		got, err := root.QueryAll(test.path)
+		if err != nil {
+			t.Errorf("%s: got error: %s", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.path, got, test.want)
+		}
+	}
+}
+
+var queryErrorTests = []struct {
+	path   string
+	offset int
+}{
+	{"", 0},
+	{"a..b", 2},
+	{"a.", 2},
+	{"a[", 2},
+	{"a[x]", 2},
+	{"a[-1]", 2},
+	{"a b", 1},
+	{`a."b`, 2},
+	{`a.'b`, 2},
+	{`a."\q"`, 2},
+	{"a.*", 2},
+	{"a[*]", 1},
+}
+
+func TestQueryError(t *testing.T) {
+	root := toml.NewTable()
+	for _, test := range queryErrorTests {
+		_, err := root.Query(test.path)
+		perr, ok := err.(*toml.PathError)
+		if !ok {
+			t.Errorf("%q: got error %#v, want *toml.PathError", test.path, err)
+			continue
+		}
+		if perr.Offset != test.offset {
+			t.Errorf("%q: got offset %d, want %d: %s", test.path, perr.Offset, test.offset, err)
+		}
+	}
+}
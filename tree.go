@@ -0,0 +1,309 @@
+package toml
+
+import (
+	"time"
+
+	"github.com/kezhuw/toml/internal/types"
+)
+
+// Value is a value in TOML document tree. It is one of String, Integer,
+// Float, Boolean, Datetime, LocalDate, LocalTime, LocalDateTime, *Array
+// and *Table. Parsed integers and floats out of range of Integer and Float
+// are Number.
+type Value interface {
+	// Type returns TOML type name of the value, for example, "string",
+	// "local date" and "table".
+	Type() string
+
+	tomlValue()
+}
+
+// String represents TOML String.
+type String string
+
+// Integer represents TOML Integer.
+type Integer int64
+
+// Float represents TOML Float.
+type Float float64
+
+// Boolean represents TOML Boolean.
+type Boolean bool
+
+// Datetime represents TOML Offset Date-Time.
+type Datetime time.Time
+
+// String returns d in RFC 3339 format.
+func (d Datetime) String() string {
+	return time.Time(d).Format(time.RFC3339Nano)
+}
+
+// MarshalTOML implements Marshaler.
+func (d Datetime) MarshalTOML() (interface{}, error) {
+	return time.Time(d), nil
+}
+
+func (s String) Type() string   { return "string" }
+func (i Integer) Type() string  { return "integer" }
+func (f Float) Type() string    { return "float" }
+func (b Boolean) Type() string  { return "boolean" }
+func (d Datetime) Type() string { return "datetime" }
+
+func (d LocalDate) Type() string      { return "local date" }
+func (t LocalTime) Type() string      { return "local time" }
+func (dt LocalDateTime) Type() string { return "local datetime" }
+
+func (s String) tomlValue()   {}
+func (i Integer) tomlValue()  {}
+func (f Float) tomlValue()    {}
+func (b Boolean) tomlValue()  {}
+func (d Datetime) tomlValue() {}
+
+func (d LocalDate) tomlValue()      {}
+func (t LocalTime) tomlValue()      {}
+func (dt LocalDateTime) tomlValue() {}
+
+// Array represents TOML Array. The zero value is an empty array ready
+// to use.
+type Array struct {
+	elems []Value
+}
+
+// NewArray returns an array containing elems.
+func NewArray(elems ...Value) *Array {
+	return &Array{elems: append([]Value(nil), elems...)}
+}
+
+func (a *Array) Type() string { return "array" }
+func (a *Array) tomlValue()   {}
+
+// Len returns number of elements in a.
+func (a *Array) Len() int {
+	return len(a.elems)
+}
+
+// Index returns the i-th element of a. It panics if i is out of range.
+func (a *Array) Index(i int) Value {
+	return a.elems[i]
+}
+
+// Elems returns a copy of elements in a.
+func (a *Array) Elems() []Value {
+	return append([]Value(nil), a.elems...)
+}
+
+// Set replaces the i-th element of a with v. It panics if i is out of
+// range or v is nil.
+func (a *Array) Set(i int, v Value) {
+	if v == nil {
+		panic("toml: set nil value to array")
+	}
+	a.elems[i] = v
+}
+
+// Append appends elems to a. It panics if any element is nil.
+func (a *Array) Append(elems ...Value) {
+	for _, v := range elems {
+		if v == nil {
+			panic("toml: append nil value to array")
+		}
+	}
+	a.elems = append(a.elems, elems...)
+}
+
+// Remove removes the i-th element of a. It panics if i is out of range.
+func (a *Array) Remove(i int) {
+	a.elems = append(a.elems[:i], a.elems[i+1:]...)
+}
+
+// MarshalTOML implements Marshaler.
+func (a *Array) MarshalTOML() (interface{}, error) {
+	return treeArrayInterface(a), nil
+}
+
+// Table represents TOML Table. Keys are kept in the order they were
+// added, that is, the order they were defined for parsed tables. The
+// zero value is an empty table ready to use.
+type Table struct {
+	keys  []string
+	elems map[string]Value
+}
+
+// NewTable returns an empty table.
+func NewTable() *Table {
+	return &Table{}
+}
+
+func (t *Table) Type() string { return "table" }
+func (t *Table) tomlValue()   {}
+
+// Len returns number of keys in t.
+func (t *Table) Len() int {
+	return len(t.keys)
+}
+
+// Keys returns keys in t in order.
+func (t *Table) Keys() []string {
+	return append([]string(nil), t.keys...)
+}
+
+// Get returns value of key in t, or nil if key is absent.
+func (t *Table) Get(key string) Value {
+	return t.elems[key]
+}
+
+// Lookup walks down nested tables in t using keys and returns the value
+// of the last key, or nil if any key is absent or any value other than
+// the last one is not a table.
+func (t *Table) Lookup(keys ...string) Value {
+	var v Value = t
+	for _, key := range keys {
+		t, ok := v.(*Table)
+		if !ok {
+			return nil
+		}
+		if v = t.elems[key]; v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// Set sets value of key in t to v. New keys are added after existing
+// ones. It panics if v is nil.
+func (t *Table) Set(key string, v Value) {
+	if v == nil {
+		panic("toml: set nil value to table")
+	}
+	if t.elems == nil {
+		t.elems = make(map[string]Value)
+	}
+	if _, ok := t.elems[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.elems[key] = v
+}
+
+// Delete removes key from t. It is a no-op if key is absent.
+func (t *Table) Delete(key string) {
+	if _, ok := t.elems[key]; !ok {
+		return
+	}
+	delete(t.elems, key)
+	for i, k := range t.keys {
+		if k == key {
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalTOML implements Marshaler. Keys of t are encoded in order.
+func (t *Table) MarshalTOML() (interface{}, error) {
+	return treeInterface(t), nil
+}
+
+// Parse parses TOML data in DefaultVersion of TOML specification and
+// returns the root table. Use Decoder to parse other versions, decoding
+// into *Table.
+func Parse(data []byte) (*Table, error) {
+	root, err := parse(data, DefaultVersion)
+	if err != nil {
+		return nil, err
+	}
+	return treeTable(root), nil
+}
+
+// ParseAll is like Parse, but continues parsing after syntax errors. It
+// skips rest of line where a syntax error occurred, and resumes at next
+// line in the table being defined. It returns the partial root table with
+// all values parsed successfully, along with *ParseErrors listing all
+// syntax errors if any. Key/value pairs on lines with syntax errors, say,
+// "x = 1x", are not kept in the partial table.
+//
+// Errors in a value spanning multiple lines, say, a multi-line string or
+// array, may cause more errors in its following lines.
+//
+// Use Decoder.ParseAll to parse other versions.
+func ParseAll(data []byte) (*Table, error) {
+	root, err := parseAll(data, DefaultVersion)
+	return treeTable(root), err
+}
+
+func treeValue(v types.Value) Value {
+	switch v := v.(type) {
+	case types.Boolean:
+		return Boolean(v)
+	case types.Integer:
+		return Integer(v.Value)
+	case types.BigInteger:
+		return Number(v)
+	case types.Float:
+		if v.Overflow {
+			return Number(v.Text)
+		}
+		return Float(v.Value)
+	case types.String:
+		return String(v)
+	case types.Datetime:
+		return Datetime(v)
+	case types.LocalDate:
+		return LocalDateOf(time.Time(v))
+	case types.LocalTime:
+		return LocalTimeOf(time.Time(v))
+	case types.LocalDatetime:
+		return LocalDateTimeOf(time.Time(v))
+	case *types.Array:
+		a := &Array{elems: make([]Value, len(v.Elems))}
+		for i, elem := range v.Elems {
+			a.elems[i] = treeValue(elem)
+		}
+		return a
+	case *types.Table:
+		return treeTable(v)
+	}
+	return nil
+}
+
+func treeTable(t *types.Table) *Table {
+	tt := &Table{keys: make([]string, 0, len(t.Elems)), elems: make(map[string]Value, len(t.Elems))}
+	for _, key := range t.Keys {
+		value, ok := t.Elems[key]
+		if !ok {
+			// Key without value due to syntax error, see ParseAll.
+			continue
+		}
+		tt.keys = append(tt.keys, key)
+		tt.elems[key] = treeValue(value)
+	}
+	return tt
+}
+
+func treeInterface(v Value) interface{} {
+	switch v := v.(type) {
+	case Boolean:
+		return bool(v)
+	case Integer:
+		return int64(v)
+	case Float:
+		return float64(v)
+	case String:
+		return string(v)
+	case Datetime:
+		return time.Time(v)
+	case *Array:
+		return treeArrayInterface(v)
+	case *Table:
+		// Encoder encodes Table in order of its keys.
+		return *v
+	}
+	return v
+}
+
+func treeArrayInterface(a *Array) []interface{} {
+	s := make([]interface{}, len(a.elems))
+	for i, elem := range a.elems {
+		s[i] = treeInterface(elem)
+	}
+	return s
+}
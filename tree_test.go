@@ -0,0 +1,317 @@
+package toml_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kezhuw/toml"
+)
+
+func TestParse(t *testing.T) {
+	in := `
+	title = "example"
+	count = 3
+	ratio = 0.5
+	enabled = true
+	born = 1979-05-27T07:32:00Z
+	day = 1979-05-27
+	ports = [ 8080, 8081 ]
+
+	[owner]
+	name = "Tom"
+	dob = { date = 1979-05-27, at = 07:32:00 }
+
+	[[products]]
+	name = "Hammer"
+
+	[[products]]
+	name = "Nail"
+	`
+	root, err := toml.Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	keys := []string{"title", "count", "ratio", "enabled", "born", "day", "ports", "owner", "products"}
+	if got := root.Keys(); !reflect.DeepEqual(got, keys) {
+		t.Errorf("keys: got %q, want %q", got, keys)
+	}
+
+	values := map[string]toml.Value{
+		"title":   toml.String("example"),
+		"count":   toml.Integer(3),
+		"ratio":   toml.Float(0.5),
+		"enabled": toml.Boolean(true),
+		"born":    toml.Datetime(time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)),
+		"day":     toml.LocalDate{1979, 5, 27},
+		"ports":   toml.NewArray(toml.Integer(8080), toml.Integer(8081)),
+	}
+	for key, want := range values {
+		got := root.Get(key)
+		if got.Type() != want.Type() {
+			t.Errorf("type of %s: got %s, want %s", key, got.Type(), want.Type())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("value of %s: got %#v, want %#v", key, got, want)
+		}
+	}
+
+	if got, want := root.Lookup("owner", "dob", "at"), (toml.LocalTime{7, 32, 0, 0}); got != want {
+		t.Errorf("owner.dob.at: got %#v, want %#v", got, want)
+	}
+	if got := root.Lookup("owner", "name", "first"); got != nil {
+		t.Errorf("owner.name.first: got %#v, want nil", got)
+	}
+
+	products, ok := root.Get("products").(*toml.Array)
+	if !ok || products.Len() != 2 {
+		t.Fatalf("products: got %#v", root.Get("products"))
+	}
+	if name := products.Index(1).(*toml.Table).Get("name"); name != toml.String("Nail") {
+		t.Errorf("products[1].name: got %#v", name)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := toml.Parse([]byte("a = "))
+	if _, ok := err.(*toml.ParseError); !ok {
+		t.Errorf("got error %#v, want *toml.ParseError", err)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	in := `a = 1
+b =
+c = 3
+
+[server]
+ip = 10.0.0.1
+port = 8080
+port = 8081
+tags = [ "a", "b" ]`
+	root, err := toml.ParseAll([]byte(in))
+	perrs, ok := err.(*toml.ParseErrors)
+	if !ok {
+		t.Fatalf("got error %#v, want *toml.ParseErrors", err)
+	}
+	var got []string
+	for _, perr := range perrs.Errors {
+		got = append(got, fmt.Sprintf("%d:%d %s", perr.Line, perr.Column, perr.Path))
+	}
+	want := []string{"2:4 b", "6:11 server.ip", "8:7 server.port"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got errors %v, want %v", got, want)
+	}
+	var perr *toml.ParseError
+	if !errors.As(err, &perr) || perr != perrs.Errors[0] {
+		t.Errorf("errors.As: got %v", perr)
+	}
+	if !errors.Is(err, perrs.Errors[2]) {
+		t.Errorf("errors.Is: expect %v in %v", perrs.Errors[2], err)
+	}
+	if keys := root.Keys(); !reflect.DeepEqual(keys, []string{"a", "c", "server"}) {
+		t.Errorf("got keys %v", keys)
+	}
+	if keys := root.Lookup("server").(*toml.Table).Keys(); !reflect.DeepEqual(keys, []string{"port", "tags"}) {
+		t.Errorf("server: got keys %v", keys)
+	}
+	if port := root.Lookup("server", "port"); port != toml.Integer(8080) {
+		t.Errorf("server.port: got %#v", port)
+	}
+
+	root, err = toml.ParseAll([]byte("a = 1"))
+	if err != nil || root.Get("a") != toml.Integer(1) {
+		t.Errorf("got %#v, error %v", root, err)
+	}
+
+	in = "x = 1x\ny.z = { a = 1 } 2\n[s]\na.b = 1\na.c = [\n  2,\n]]\nd = 4"
+	root, err = toml.ParseAll([]byte(in))
+	if perrs, ok := err.(*toml.ParseErrors); !ok || len(perrs.Errors) != 3 {
+		t.Errorf("got error %#v, want *toml.ParseErrors with 3 errors", err)
+	}
+	if keys := root.Keys(); !reflect.DeepEqual(keys, []string{"s"}) {
+		t.Errorf("got keys %v, want [s]", keys)
+	}
+	if keys := root.Lookup("s", "a").(*toml.Table).Keys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Errorf("s.a: got keys %v, want [b]", keys)
+	}
+	if d := root.Lookup("s", "d"); d != toml.Integer(4) {
+		t.Errorf("s.d: got %#v", d)
+	}
+
+	in = "a = 07:32\nb =\nc = 3"
+	for _, test := range []struct {
+		version toml.Version
+		lines   []int
+		keys    []string
+	}{
+		{toml.V1_0_0, []int{1, 2}, []string{"c"}},
+		{toml.V1_1_0, []int{2}, []string{"a", "c"}},
+	} {
+		dec := toml.NewDecoder(strings.NewReader(in))
+		dec.SetVersion(test.version)
+		root, err := dec.ParseAll()
+		perrs, ok := err.(*toml.ParseErrors)
+		if !ok {
+			t.Errorf("%s: got error %#v, want *toml.ParseErrors", test.version, err)
+			continue
+		}
+		var lines []int
+		for _, perr := range perrs.Errors {
+			lines = append(lines, perr.Line)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%s: got error lines %v, want %v", test.version, lines, test.lines)
+		}
+		if keys := root.Keys(); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%s: got keys %v, want %v", test.version, keys, test.keys)
+		}
+	}
+}
+
+func TestTreeModify(t *testing.T) {
+	root, err := toml.Parse([]byte("b = 1\na = [ 1, 2, 3 ]\nc = 3"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	root.Set("b", toml.String("two"))
+	root.Delete("c")
+	root.Delete("missing")
+	a := root.Get("a").(*toml.Array)
+	a.Remove(0)
+	a.Set(0, toml.Integer(20))
+	a.Append(toml.Integer(4))
+
+	server := toml.NewTable()
+	server.Set("ip", toml.String("10.0.0.1"))
+	server.Set("port", toml.Integer(8080))
+	root.Set("server", server)
+
+	if got, want := root.Keys(), []string{"b", "a", "server"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys: got %q, want %q", got, want)
+	}
+	if got, want := a.Elems(), []toml.Value{toml.Integer(20), toml.Integer(3), toml.Integer(4)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a: got %#v, want %#v", got, want)
+	}
+
+	b, err := toml.Marshal(root)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := `b = "two"
+a = [ 20, 3, 4 ]
+
+[server]
+ip = "10.0.0.1"
+port = 8080
+`
+	if got := string(b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := toml.Parse(b)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := again.Lookup("server", "port"); got != toml.Integer(8080) {
+		t.Errorf("server.port: got %#v", got)
+	}
+}
+
+func TestMarshalTreeOrder(t *testing.T) {
+	in := `b = 1
+a = { y = 2, x = 1 }
+
+[z]
+d = [ { n = 2, m = 1 } ]
+
+[[products]]
+sku = 1
+name = "Hammer"
+
+[c]
+`
+	root, err := toml.Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	b, err := toml.Marshal(root)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	want := `b = 1
+
+[a]
+y = 2
+x = 1
+
+[z]
+
+[[z.d]]
+n = 2
+m = 1
+
+[[products]]
+sku = 1
+name = "Hammer"
+
+[c]
+`
+	if got := string(b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnmarshalTree(t *testing.T) {
+	in := `
+	name = "app"
+	born = 1979-05-27T07:32:00Z
+	ports = [ 80, 443 ]
+
+	[plugins.auth]
+	enabled = true
+	`
+	var out struct {
+		Name    toml.Value
+		Born    toml.Datetime
+		Ports   *toml.Array
+		Plugins *toml.Table
+	}
+	if err := toml.Unmarshal([]byte(in), &out); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if out.Name != toml.String("app") {
+		t.Errorf("name: got %#v", out.Name)
+	}
+	if got, want := time.Time(out.Born), time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("born: got %s, want %s", got, want)
+	}
+	if out.Ports == nil || out.Ports.Len() != 2 || out.Ports.Index(1) != toml.Integer(443) {
+		t.Errorf("ports: got %#v", out.Ports)
+	}
+	if out.Plugins == nil || out.Plugins.Lookup("auth", "enabled") != toml.Boolean(true) {
+		t.Errorf("plugins: got %#v", out.Plugins)
+	}
+
+	var bad struct{ Name *toml.Table }
+	err := toml.Unmarshal([]byte(`name = "app"`), &bad)
+	if _, ok := err.(*toml.UnmarshalTypeError); !ok {
+		t.Errorf("got error %#v, want *toml.UnmarshalTypeError", err)
+	}
+}
+
+func TestDecoderTree(t *testing.T) {
+	dec := toml.NewDecoder(strings.NewReader("point = { x = 1, y = 2, }"))
+	dec.SetVersion(toml.V1_1_0)
+	var root *toml.Table
+	if err := dec.Decode(&root); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if got := root.Lookup("point", "y"); got != toml.Integer(2) {
+		t.Errorf("point.y: got %#v", got)
+	}
+}
@@ -0,0 +1,35 @@
+package toml
+
+// Version specifies version of TOML specification.
+type Version int
+
+// Supported versions of TOML specification. Features introduced in newer
+// versions are rejected when encoding or decoding with older versions.
+const (
+	V0_4_0 Version = iota + 1
+	V1_0_0
+	V1_1_0
+)
+
+// DefaultVersion is the version of TOML specification used if none was
+// specified.
+const DefaultVersion = V1_0_0
+
+func (v Version) normalize() Version {
+	if v == 0 {
+		return DefaultVersion
+	}
+	return v
+}
+
+func (v Version) String() string {
+	switch v.normalize() {
+	case V0_4_0:
+		return "v0.4.0"
+	case V1_0_0:
+		return "v1.0.0"
+	case V1_1_0:
+		return "v1.1.0"
+	}
+	return "unknown version"
+}